@@ -16,4 +16,4 @@ CODEGEN_PKG=${CODEGEN_PKG:-$(
 #                  instead of the $GOPATH directly. For normal projects this can be dropped.
 bash "${CODEGEN_PKG}"/generate-groups.sh all \
   github.com/ca-gip/kubi-members/pkg/generated github.com/ca-gip/kubi-members/pkg/apis \
-  cagip:v1 --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
+  cagip:v1,v2 --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ca-gip/kubi-members/internal/export"
+	"github.com/ca-gip/kubi-members/internal/utils"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	membersinformers "github.com/ca-gip/kubi-members/pkg/generated/informers/externalversions/cagip/v2"
+	memberslisters "github.com/ca-gip/kubi-members/pkg/generated/listers/cagip/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Server serves the access matrix read only from the informer caches:
+//
+//	GET /users/{id}                 namespaces and cluster role of a user, by uid, username or mail
+//	GET /namespaces/{ns}/members    members of a namespace
+//	GET /clustermembers?role=       cluster members, optionally of a single role
+//
+// Lists are paginated with the limit and continue query parameters and every
+// response carries an ETag honoured through If-None-Match.
+type Server struct {
+	clusterMembersLister memberslisters.ClusterMemberLister
+	projectMembersLister memberslisters.ProjectMemberLister
+	synced               []cache.InformerSynced
+}
+
+// Member is the representation of a ProjectMember or ClusterMember
+type Member struct {
+	Name           string                 `json:"name"`
+	Namespace      string                 `json:"namespace,omitempty"`
+	UID            string                 `json:"uid"`
+	Username       string                 `json:"username"`
+	Mail           string                 `json:"mail"`
+	DisplayName    string                 `json:"displayName,omitempty"`
+	Attributes     map[string]string      `json:"attributes,omitempty"`
+	Dn             string                 `json:"dn"`
+	Role           string                 `json:"role"`
+	Roles          []v2.ClusterMemberRole `json:"roles,omitempty"`
+	LastSyncedTime *metav1.Time           `json:"lastSyncedTime,omitempty"`
+}
+
+// MemberList is a page of members, Continue being the token of the next page
+type MemberList struct {
+	Items    []Member `json:"items"`
+	Total    int      `json:"total"`
+	Continue string   `json:"continue,omitempty"`
+}
+
+func NewServer(clusterMemberInformer membersinformers.ClusterMemberInformer, projectMemberInformer membersinformers.ProjectMemberInformer) *Server {
+	return &Server{
+		clusterMembersLister: clusterMemberInformer.Lister(),
+		projectMembersLister: projectMemberInformer.Lister(),
+		synced:               []cache.InformerSynced{clusterMemberInformer.Informer().HasSynced, projectMemberInformer.Informer().HasSynced},
+	}
+}
+
+// Handler returns the handler of the API routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", s.getUser)
+	mux.HandleFunc("/namespaces/", s.listNamespaceMembers)
+	mux.HandleFunc("/clustermembers", s.listClusterMembers)
+	return s.readOnly(mux)
+}
+
+// Serve serves handler on addr until stopCh is closed
+func Serve(addr string, handler http.Handler, stopCh <-chan struct{}) {
+	server := &http.Server{Addr: addr, Handler: handler}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	klog.Infof("Serving HTTP on %s", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		klog.Fatalf("Could not serve HTTP on %s : %s", addr, err)
+	}
+}
+
+// readOnly rejects writes and requests received before the caches are synced
+func (s *Server) readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "read only API")
+			return
+		}
+		for _, synced := range s.synced {
+			if !synced() {
+				writeError(w, http.StatusServiceUnavailable, "caches are not synced yet")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/users/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	clusterMembers, projectMembers, err := s.list()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	matrix := export.Build(clusterMembers, projectMembers, export.Filter{User: id})
+	rows := matrix.Rows
+	for _, row := range matrix.Rows {
+		// The uid identifies a single user, unlike usernames and mails
+		if row.UID == id {
+			rows = []export.Row{row}
+			break
+		}
+	}
+	if len(rows) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("user %s not found", id))
+		return
+	}
+	if len(rows) > 1 {
+		uids := make([]string, 0, len(rows))
+		for _, row := range rows {
+			uids = append(uids, row.UID)
+		}
+		writeError(w, http.StatusConflict, fmt.Sprintf("%s matches %d users, query one of the uids %s", id, len(rows), strings.Join(uids, ", ")))
+		return
+	}
+
+	row := rows[0]
+	user := struct {
+		UID         string            `json:"uid"`
+		Username    string            `json:"username"`
+		Mail        string            `json:"mail"`
+		Dn          string            `json:"dn"`
+		ClusterRole string            `json:"clusterRole,omitempty"`
+		Namespaces  map[string]string `json:"namespaces"`
+	}{UID: row.UID, Username: row.Username, Mail: row.Mail, Dn: row.Dn, Namespaces: map[string]string{}}
+	for scope, role := range row.Roles {
+		if scope == export.ClusterColumn {
+			user.ClusterRole = role
+		} else {
+			user.Namespaces[scope] = role
+		}
+	}
+	writeJSON(w, r, user)
+}
+
+func (s *Server) listNamespaceMembers(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/namespaces/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "members" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	projectMembers, err := s.projectMembersLister.ProjectMembers(parts[0]).List(utils.DefaultLabelSelector())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	members := make([]Member, 0, len(projectMembers))
+	for _, member := range projectMembers {
+		members = append(members, Member{
+			Name:           member.Name,
+			Namespace:      member.Namespace,
+			UID:            member.Spec.UID,
+			Username:       member.Spec.Username,
+			Mail:           member.Spec.Mail,
+			DisplayName:    member.Spec.DisplayName,
+			Attributes:     member.Spec.Attributes,
+			Dn:             member.Spec.Dn,
+			Role:           member.Spec.Role,
+			LastSyncedTime: member.Status.LastSyncedTime,
+		})
+	}
+	s.writePage(w, r, members)
+}
+
+func (s *Server) listClusterMembers(w http.ResponseWriter, r *http.Request) {
+	clusterMembers, err := s.clusterMembersLister.List(utils.DefaultLabelSelector())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	role := r.URL.Query().Get("role")
+	members := make([]Member, 0, len(clusterMembers))
+	for _, member := range clusterMembers {
+		if role != "" && member.Spec.Role != role {
+			continue
+		}
+		members = append(members, Member{
+			Name:           member.Name,
+			UID:            member.Spec.UID,
+			Username:       member.Spec.Username,
+			Mail:           member.Spec.Mail,
+			DisplayName:    member.Spec.DisplayName,
+			Attributes:     member.Spec.Attributes,
+			Dn:             member.Spec.Dn,
+			Role:           member.Spec.Role,
+			Roles:          member.Spec.Roles,
+			LastSyncedTime: member.Status.LastSyncedTime,
+		})
+	}
+	s.writePage(w, r, members)
+}
+
+func (s *Server) list() ([]*v2.ClusterMember, []*v2.ProjectMember, error) {
+	clusterMembers, err := s.clusterMembersLister.List(utils.DefaultLabelSelector())
+	if err != nil {
+		return nil, nil, err
+	}
+	projectMembers, err := s.projectMembersLister.List(utils.DefaultLabelSelector())
+	if err != nil {
+		return nil, nil, err
+	}
+	return clusterMembers, projectMembers, nil
+}
+
+// writePage writes the page of members selected by the limit and continue
+// parameters, the continue token being the offset of the next page
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, members []Member) {
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+
+	limit := defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	offset := 0
+	if value := r.URL.Query().Get("continue"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > len(members) {
+			writeError(w, http.StatusBadRequest, "invalid continue token")
+			return
+		}
+		offset = parsed
+	}
+
+	end := offset + limit
+	page := MemberList{Total: len(members)}
+	if end < len(members) {
+		page.Continue = strconv.Itoa(end)
+	} else {
+		end = len(members)
+	}
+	page.Items = members[offset:end]
+	writeJSON(w, r, page)
+}
+
+// writeJSON writes value with an ETag, or only a 304 when the client already has it
+func writeJSON(w http.ResponseWriter, r *http.Request, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match == "*" || strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		klog.V(4).Infof("Could not write API response : %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
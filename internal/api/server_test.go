@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	memberslisters "github.com/ca-gip/kubi-members/pkg/generated/listers/cagip/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newTestServer(t *testing.T, members ...*v2.ProjectMember) *Server {
+	t.Helper()
+	projectMembers := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, member := range members {
+		if err := projectMembers.Add(member); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Server{
+		clusterMembersLister: memberslisters.NewClusterMemberLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		projectMembersLister: memberslisters.NewProjectMemberLister(projectMembers),
+		synced:               []cache.InformerSynced{func() bool { return true }},
+	}
+}
+
+func projectMember(namespace, uid, username, mail, role string) *v2.ProjectMember {
+	return &v2.ProjectMember{
+		ObjectMeta: metav1.ObjectMeta{Name: uid, Namespace: namespace},
+		Spec:       v2.ProjectMemberSpec{UID: uid, Username: username, Mail: mail, Role: role},
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	server := newTestServer(t,
+		projectMember("team-dev", "jdoe", "jdoe.smith", "jdoe@example.org", "admin"),
+		projectMember("team-ops", "jdoe", "jdoe.smith", "jdoe@example.org", "view"),
+		projectMember("team-dev", "jdoe2", "jdoe.smith", "john.doe@example.org", "view"),
+		projectMember("team-ops", "asmith", "jdoe", "asmith@example.org", "admin"),
+	)
+
+	tests := []struct {
+		name       string
+		id         string
+		status     int
+		namespaces map[string]string
+	}{
+		{"by uid", "jdoe2", http.StatusOK, map[string]string{"team-dev": "view"}},
+		{"by mail", "jdoe@example.org", http.StatusOK, map[string]string{"team-dev": "admin", "team-ops": "view"}},
+		{"uid preferred over a username", "jdoe", http.StatusOK, map[string]string{"team-dev": "admin", "team-ops": "view"}},
+		{"ambiguous username", "jdoe.smith", http.StatusConflict, nil},
+		{"unknown", "nobody", http.StatusNotFound, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/"+test.id, nil))
+			if recorder.Code != test.status {
+				t.Fatalf("status %d, want %d : %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			user := struct {
+				Namespaces map[string]string `json:"namespaces"`
+			}{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &user); err != nil {
+				t.Fatal(err)
+			}
+			if len(user.Namespaces) != len(test.namespaces) {
+				t.Fatalf("namespaces %v, want %v", user.Namespaces, test.namespaces)
+			}
+			for namespace, role := range test.namespaces {
+				if user.Namespaces[namespace] != role {
+					t.Errorf("namespaces %v, want %v", user.Namespaces, test.namespaces)
+				}
+			}
+		})
+	}
+}
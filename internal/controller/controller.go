@@ -3,235 +3,769 @@ package controller
 import (
 	"context"
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ca-gip/kubi-members/internal/ldap"
+	"github.com/ca-gip/kubi-members/internal/metrics"
 	"github.com/ca-gip/kubi-members/internal/utils"
-	v1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
 	membersclientset "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned"
+	membersinformers "github.com/ca-gip/kubi-members/pkg/generated/informers/externalversions/cagip/v2"
+	memberslisters "github.com/ca-gip/kubi-members/pkg/generated/listers/cagip/v2"
 	kubiv1 "github.com/ca-gip/kubi/pkg/apis/cagip/v1"
 	projectclientset "github.com/ca-gip/kubi/pkg/generated/clientset/versioned"
+	projectinformers "github.com/ca-gip/kubi/pkg/generated/informers/externalversions/cagip/v1"
+	projectlisters "github.com/ca-gip/kubi/pkg/generated/listers/cagip/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	rbacinformers "k8s.io/client-go/informers/rbac/v1"
 	"k8s.io/client-go/kubernetes"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
 
+// Options tunes the behaviour of the controller
+type Options struct {
+	// ResyncInterval is the interval between two LDAP polls of every project and cluster role
+	ResyncInterval time.Duration
+	// MaxDeletionPercent is the largest share of the members of a project, or of the
+	// cluster, that a single sync may delete. Larger deletions are refused.
+	MaxDeletionPercent int
+	// DeletionGuardMin is the number of deletions below which MaxDeletionPercent is not enforced
+	DeletionGuardMin int
+	// ForceDeletions applies the deletions MaxDeletionPercent would refuse
+	ForceDeletions bool
+	// RBAC enables the generation of RoleBindings and ClusterRoleBindings from members when set
+	RBAC *utils.RBACConfig
+}
+
 type Controller struct {
 	configmapclientset kubernetes.Interface
 	projectclientset   projectclientset.Interface
 	membersclientset   membersclientset.Interface
-	projectsMembers    map[string][]*v1.ProjectMember
-	clusterMembers     []*v1.ClusterMember
+	projectsMembers    map[string][]*v2.ProjectMember
+	clusterMembers     []*v2.ClusterMember
+	// failedRoles holds the roles whose LDAP lookup failed during the current
+	// sync, their existing members are left untouched
+	failedRoles map[string]error
+	// failedProjects holds the projects whose members could not be computed by
+	// LocalSyncProjectsMembers, reported once the sync is applied
+	failedProjects map[string]error
+
+	projectsLister       projectlisters.ProjectLister
+	projectsSynced       cache.InformerSynced
+	clusterMembersLister memberslisters.ClusterMemberLister
+	clusterMembersSynced cache.InformerSynced
+	projectMembersLister memberslisters.ProjectMemberLister
+	projectMembersSynced cache.InformerSynced
+	// RoleBinding and ClusterRoleBinding listers are only set when RBAC generation is enabled
+	roleBindingsLister        rbaclisters.RoleBindingLister
+	clusterRoleBindingsLister rbaclisters.ClusterRoleBindingLister
+	cacheSyncs                []cache.InformerSynced
+
+	// workqueue holds the names of the projects to reconcile, plus
+	// utils.ClusterMembersKey when cluster members must be recomputed.
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+	// broadcaster sends the Events of recorder
+	broadcaster record.EventBroadcaster
+	options     Options
+	// reloadMu is held for reading by each reconciliation, and for writing
+	// while a new configuration is applied
+	reloadMu sync.RWMutex
 
 	ldap *ldap.Ldap
 }
 
-func NewController(configMapClient kubernetes.Interface, projectClient projectclientset.Interface, membersClient membersclientset.Interface, ldap *ldap.Ldap) *Controller {
-	return &Controller{
-		configmapclientset: configMapClient,
-		projectclientset:   projectClient,
-		membersclientset:   membersClient,
-		ldap:               ldap,
+func NewController(configMapClient kubernetes.Interface, projectClient projectclientset.Interface, membersClient membersclientset.Interface,
+	projectInformer projectinformers.ProjectInformer, clusterMemberInformer membersinformers.ClusterMemberInformer, projectMemberInformer membersinformers.ProjectMemberInformer,
+	roleBindingInformer rbacinformers.RoleBindingInformer, clusterRoleBindingInformer rbacinformers.ClusterRoleBindingInformer,
+	ldap *ldap.Ldap, options Options) *Controller {
+
+	c := &Controller{
+		configmapclientset:   configMapClient,
+		projectclientset:     projectClient,
+		membersclientset:     membersClient,
+		projectsLister:       projectInformer.Lister(),
+		projectsSynced:       projectInformer.Informer().HasSynced,
+		clusterMembersLister: clusterMemberInformer.Lister(),
+		clusterMembersSynced: clusterMemberInformer.Informer().HasSynced,
+		projectMembersLister: projectMemberInformer.Lister(),
+		projectMembersSynced: projectMemberInformer.Informer().HasSynced,
+		workqueue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Members"),
+		options:              options,
+		ldap:                 ldap,
+	}
+	c.startEventRecorder(configMapClient)
+	c.cacheSyncs = []cache.InformerSynced{c.projectsSynced, c.clusterMembersSynced, c.projectMembersSynced}
+
+	if options.RBAC != nil {
+		c.roleBindingsLister = roleBindingInformer.Lister()
+		c.clusterRoleBindingsLister = clusterRoleBindingInformer.Lister()
+		c.cacheSyncs = append(c.cacheSyncs, roleBindingInformer.Informer().HasSynced, clusterRoleBindingInformer.Informer().HasSynced)
 	}
+
+	projectInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueProject,
+		UpdateFunc: func(old, new interface{}) {
+			oldProject := old.(*kubiv1.Project)
+			newProject := new.(*kubiv1.Project)
+			if oldProject.ResourceVersion == newProject.ResourceVersion {
+				// Periodic informer resyncs are handled by the resync loop
+				return
+			}
+			c.enqueueProject(new)
+		},
+		// The reconciliation of a deleted project forgets its metrics
+		DeleteFunc: c.enqueueDeletedProject,
+	})
+
+	return c
 }
 
 func (c *Controller) Preflight() {
 }
 
-func (c *Controller) Run() (err error) {
+// WaitForCacheSync blocks until the informers backing the controller listers are synced
+func (c *Controller) WaitForCacheSync(stopCh <-chan struct{}) error {
+	if ok := cache.WaitForCacheSync(stopCh, c.cacheSyncs...); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	return nil
+}
+
+// Run reconciles members continuously until stopCh is closed. Projects are
+// reconciled when they are created or updated, and LDAP is polled again for
+// every project and for cluster members each resyncInterval.
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	klog.Info("Waiting for informer caches to sync")
+	if err := c.WaitForCacheSync(stopCh); err != nil {
+		return err
+	}
+
+	klog.Infof("Starting %d workers, resync interval %s", workers, c.options.ResyncInterval)
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+	go wait.Until(c.enqueueAll, c.options.ResyncInterval, stopCh)
+
+	<-stopCh
+	klog.Info("Shutting down workers")
+
+	return nil
+}
+
+func (c *Controller) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.workqueue.Done(obj)
+
+	key, ok := obj.(string)
+	if !ok {
+		c.workqueue.Forget(obj)
+		utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
+		return true
+	}
+
+	c.reloadMu.RLock()
+	err := c.reconcile(key)
+	c.reloadMu.RUnlock()
+	if err != nil && deletionsRefused(err) {
+		// Retrying would refuse the same deletions, they wait for the next resync
+		c.workqueue.Forget(obj)
+		utilruntime.HandleError(fmt.Errorf("error syncing '%s': %s, waiting for the next resync", key, err.Error()))
+		return true
+	}
+	if err != nil {
+		c.workqueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error()))
+		return true
+	}
+
+	c.workqueue.Forget(obj)
+	return true
+}
+
+func (c *Controller) reconcile(key string) error {
+	if key == utils.ClusterMembersKey {
+		return c.reconcileClusterMembers()
+	}
+
+	project, err := c.projectsLister.Get(key)
+	if errors.IsNotFound(err) {
+		// ProjectMembers are garbage collected through their owner reference
+		klog.V(4).Infof("Project %s no longer exists", key)
+		metrics.DeleteProjectMembers(key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if project.Status.Name != kubiv1.ProjectStatusCreated {
+		klog.V(4).Infof("Project %s is not created yet, skipping", key)
+		return nil
+	}
+
+	return c.reconcileProjectMembers(project)
+}
+
+func (c *Controller) reconcileClusterMembers() (err error) {
+	defer func(start time.Time) { metrics.ObserveSync("cluster", start, err) }(time.Now())
+
+	c.clusterMembers = []*v2.ClusterMember{}
+	c.failedRoles = make(map[string]error)
+
+	// Roles whose lookup failed are kept as is while the others are applied
+	err = utilerrors.NewAggregate([]error{c.LocalSyncClusterMembers(), c.SyncClusterMembers()})
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Cluster members reconciled, %d members", len(c.clusterMembers))
+	return nil
+}
+
+func (c *Controller) reconcileProjectMembers(project *kubiv1.Project) (err error) {
+	defer func(start time.Time) {
+		metrics.ObserveSync("project", start, err)
+		metrics.ObserveProjectSync(project.Name, err)
+	}(time.Now())
+
+	members, err := c.localSyncProjectMembers(project)
+	if err != nil {
+		c.searchFailedEvent(project.Name, err)
+		c.markProjectMembersUnavailable(project.Name, err)
+		return fmt.Errorf("keeping previous members of project %s : %w", project.Name, err)
+	}
+
+	if err = c.syncProjectMembers(project.Name, members); err != nil {
+		return err
+	}
+
+	klog.Infof("Project %s reconciled, %d members", project.Name, len(members))
+	return nil
+}
 
-	c.clusterMembers = []*v1.ClusterMember{}
-	c.projectsMembers = make(map[string][]*v1.ProjectMember)
+func (c *Controller) enqueueProject(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
 
-	err = c.LocalSyncClusterMembers()
+func (c *Controller) enqueueDeletedProject(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
-		klog.Fatalf("Could not local compute cluster members : %v", err)
+		utilruntime.HandleError(err)
 		return
 	}
+	c.workqueue.Add(key)
+}
 
-	err = c.LocalSyncProjectsMembers()
+// enqueueAll schedules a reconciliation of cluster members and of every project,
+// users are fetched again from LDAP during this new sync
+func (c *Controller) enqueueAll() {
+	c.ldap.ResetCache()
+	c.workqueue.Add(utils.ClusterMembersKey)
+
+	projects, err := c.projectsLister.List(utils.DefaultLabelSelector())
 	if err != nil {
-		klog.Fatalf("Could not local compute project members: %v", err)
+		klog.Errorf(utils.CouldNotList, "projects")
 		return
 	}
+	for _, project := range projects {
+		c.enqueueProject(project)
+	}
+}
+
+// Reload applies a new configuration once the running reconciliations are
+// done, then reconciles every project and cluster members with it
+func (c *Controller) Reload(config utils.Config) error {
+	c.reloadMu.Lock()
+	err := c.ldap.Reload(config)
+	if err == nil && c.options.RBAC != nil {
+		*c.options.RBAC = config.RBAC
+	}
+	c.reloadMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	c.enqueueAll()
+	return nil
+}
+
+// RunOnce computes members a single time, writes them and returns
+func (c *Controller) RunOnce() (err error) {
+	defer func(start time.Time) { metrics.ObserveSync("all", start, err) }(time.Now())
+
+	c.ldap.ResetCache()
 
-	c.SyncClusterMembers()
-	c.SyncProjectMembers()
+	c.clusterMembers = []*v2.ClusterMember{}
+	c.projectsMembers = make(map[string][]*v2.ProjectMember)
+	c.failedRoles = make(map[string]error)
+	c.failedProjects = make(map[string]error)
+
+	// Groups whose lookup failed are left untouched, the errors are reported
+	// once every other change is applied
+	clusterErr := c.LocalSyncClusterMembers()
+	projectsErr := c.LocalSyncProjectsMembers()
+
+	clusterSyncErr := c.SyncClusterMembers()
+	projectsSyncErr := c.SyncProjectMembers()
+	for namespace, err := range c.failedProjects {
+		c.searchFailedEvent(namespace, err)
+		c.markProjectMembersUnavailable(namespace, err)
+		metrics.ObserveProjectSync(namespace, err)
+	}
+
+	err = utilerrors.NewAggregate([]error{clusterErr, projectsErr, clusterSyncErr, projectsSyncErr})
+	if err != nil {
+		return
+	}
 
 	klog.Infof("Update members job complete.")
 
 	return
 }
 
-func (c *Controller) SyncClusterMembers() {
-	err := c.membersclientset.CagipV1().ClusterMembers().DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{})
+// SyncClusterMembers applies the computed cluster members: missing members are
+// created, changed members updated and members no longer found in LDAP deleted.
+// Unchanged members are left untouched.
+func (c *Controller) SyncClusterMembers() error {
+	countByRole := map[string]int{}
+	for _, member := range c.clusterMembers {
+		countByRole[member.Spec.Role]++
+	}
+	metrics.SetClusterMembers(countByRole)
+
+	existingMembers, err := c.clusterMembersLister.List(utils.DefaultLabelSelector())
 	if err != nil {
-		klog.Error("Could not delete cluster members ", err)
+		klog.Errorf(utils.CouldNotList, "cluster members")
+		return err
+	}
 
+	existing := make(map[string]*v2.ClusterMember, len(existingMembers))
+	for _, member := range existingMembers {
+		existing[member.Name] = member
 	}
+
 	for _, member := range c.clusterMembers {
-		_, err := c.membersclientset.CagipV1().ClusterMembers().Create(context.TODO(), member, metav1.CreateOptions{})
+		current, found := existing[member.Name]
+		delete(existing, member.Name)
+
+		if !found {
+			created, err := c.membersclientset.CagipV2().ClusterMembers().Create(context.TODO(), member, metav1.CreateOptions{})
+			if err != nil {
+				klog.Errorf("Could not create cluster member %s : %s", member.Spec.Username, err)
+				continue
+			}
+			metrics.MemberChanged("ClusterMember", "create")
+			c.updateClusterMemberStatus(created, syncedStatus(created.Status, created.Generation, "", clusterMemberGroups(created)))
+			c.syncClusterMemberBinding(created)
+			continue
+		}
+
+		if err := c.failedRole(current); err != nil {
+			c.updateClusterMemberStatus(current, unavailableStatus(current.Status, current.Generation, err))
+			continue
+		}
+
+		if clusterMemberEqual(current, member) {
+			c.updateClusterMemberStatus(current, syncedStatus(current.Status, current.Generation, "", clusterMemberGroups(current)))
+			c.syncClusterMemberBinding(current)
+			continue
+		}
+
+		updated := current.DeepCopy()
+		updated.Spec = member.Spec
+		updated, err := c.membersclientset.CagipV2().ClusterMembers().Update(context.TODO(), updated, metav1.UpdateOptions{})
 		if err != nil {
-			klog.Errorf("Could not create cluster member %s", member.Username, err)
+			klog.Errorf("Could not update cluster member %s : %s", member.Spec.Username, err)
+			continue
+		}
+		metrics.MemberChanged("ClusterMember", "update")
+		if current.Spec.Role != updated.Spec.Role {
+			c.recorder.Eventf(updated, corev1.EventTypeNormal, ReasonRoleChanged, "Role of %s changed from %s to %s", updated.Spec.Username, current.Spec.Role, updated.Spec.Role)
+		}
+		c.updateClusterMemberStatus(updated, syncedStatus(updated.Status, updated.Generation, "", clusterMemberGroups(updated)))
+		c.syncClusterMemberBinding(updated)
+	}
+
+	for name, member := range existing {
+		if err := c.failedRole(member); err != nil {
+			klog.Warningf("Keeping cluster member %s, the lookup of one of its roles failed", member.Spec.Username)
+			c.updateClusterMemberStatus(member, unavailableStatus(member.Status, member.Generation, err))
+			delete(existing, name)
+		}
+	}
+
+	if err := c.allowDeletions("the cluster", len(existing), len(existingMembers)); err != nil {
+		for _, member := range existing {
+			c.recorder.Eventf(member, corev1.EventTypeWarning, ReasonDeletionsRefused, "Not deleted, %s, see --force-deletions", err)
+		}
+		return err
+	}
+
+	for name, member := range existing {
+		err := c.membersclientset.CagipV2().ClusterMembers().Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("Could not delete cluster member %s : %s", member.Spec.Username, err)
+			continue
 		}
+		metrics.MemberChanged("ClusterMember", "delete")
 	}
+	return nil
+}
+
+func clusterMemberEqual(a, b *v2.ClusterMember) bool {
+	return equality.Semantic.DeepEqual(a.Spec, b.Spec)
 }
 
-func (c *Controller) SyncProjectMembers() {
-	c.clearProjectsMembers()
+func (c *Controller) SyncProjectMembers() error {
+	var errs []error
 	for project, members := range c.projectsMembers {
-		c.createProjectMembers(project, members)
+		err := c.syncProjectMembers(project, members)
+		metrics.ObserveProjectSync(project, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-
+	return utilerrors.NewAggregate(errs)
 }
 
 func (c *Controller) LocalSyncProjectsMembers() error {
-	projects, err := c.projectclientset.CagipV1().Projects().List(context.TODO(), metav1.ListOptions{})
+	projects, err := c.projectsLister.List(utils.DefaultLabelSelector())
 	if err != nil {
 		klog.Errorf("Could not list project : %s", err)
 		return err
 	}
-	for _, project := range projects.Items {
+	var errs []error
+	for _, project := range projects {
 		if project.Status.Name == kubiv1.ProjectStatusCreated {
-			members, err := c.ldap.Search(project.Spec.SourceDN)
+			members, err := c.localSyncProjectMembers(project)
 			if err != nil {
-				klog.Errorf("Could not find ldap members for %s : %s", project.Spec.SourceDN, err)
+				c.failedProjects[project.Name] = err
+				errs = append(errs, fmt.Errorf("keeping previous members of project %s : %w", project.Name, err))
+				continue
 			}
-			c.projectsMembers[project.Name] = c.templateProjectMembers(&project, members)
+			c.projectsMembers[project.Name] = members
 		}
 	}
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
-func (c *Controller) LocalSyncClusterMembers() error {
-	if c.ldap.OpsGroupBase != "" {
-		opsUsers, err := c.ldap.Search(c.ldap.OpsGroupBase)
-		if err != nil {
-			klog.Errorf("Could not find ldap members for %s : %s", c.ldap.OpsGroupBase, err)
-		}
-		c.synchronizeClusterMembersByRole(opsUsers, utils.OpsRole)
-	} else {
-		klog.Warningf("Ignored role %v has it was not specified in configuration", utils.OpsRole)
+// localSyncProjectMembers computes the members of a project from the groups of
+// each of its roles, each user getting the role with the highest priority
+func (c *Controller) localSyncProjectMembers(project *kubiv1.Project) ([]*v2.ProjectMember, error) {
+	roles, err := utils.ProjectRoles(project.Spec.SourceDN, project.Annotations, c.ldap.ProjectDefaultRole)
+	if err != nil {
+		klog.Errorf("Could not read roles of project %s : %s", project.Name, err)
+		return nil, err
 	}
 
-	if c.ldap.AppGroupBase != "" {
-		appUsers, err := c.ldap.Search(c.ldap.AppGroupBase)
-		if err != nil {
-			klog.Errorf("Could not find ldap members for %s : %s", c.ldap.AppGroupBase, err)
+	var members []*v2.ProjectMember
+	index := map[string]int{}
+	for _, role := range roles {
+		for _, groupDN := range role.GroupDNs {
+			users, err := c.ldap.Search(groupDN)
+			if err != nil {
+				klog.Errorf("Could not find ldap members for %s : %s", groupDN, err)
+				return nil, err
+			}
+
+			for _, user := range users {
+				i, found := index[memberName(user)]
+				if !found {
+					index[memberName(user)] = len(members)
+					members = append(members, c.templateProjectMember(project, user, role.Name, groupDN))
+					continue
+				}
+
+				member := members[i]
+				member.Status.SourceGroups = appendGroup(member.Status.SourceGroups, groupDN)
+				// Change user Role only if current has less privileges
+				memberRole, _ := roles.Get(member.Spec.Role)
+				if memberRole.Priority < role.Priority {
+					member.Spec.Role = role.Name
+				}
+			}
 		}
-		c.synchronizeClusterMembersByRole(appUsers, utils.AppRole)
-	} else {
-		klog.Warningf("Ignored role %v has it was not specified in configuration", utils.AppRole)
 	}
+	return members, nil
+}
 
-	if c.ldap.CustomerGroupBase != "" {
-		customerUsers, err := c.ldap.Search(c.ldap.CustomerGroupBase)
-		if err != nil {
-			klog.Errorf("Could not find ldap members for %s : %s", c.ldap.CustomerGroupBase, err)
+func appendGroup(groups []string, groupDN string) []string {
+	for _, group := range groups {
+		if group == groupDN {
+			return groups
 		}
-		c.synchronizeClusterMembersByRole(customerUsers, utils.CustomerRole)
-	} else {
-		klog.Warningf("Ignored role %v has it was not specified in configuration", utils.CustomerRole)
 	}
+	return append(groups, groupDN)
+}
 
-	if c.ldap.AdminGroupBase != "" {
-		adminsUsers, err := c.ldap.Search(c.ldap.AdminGroupBase)
-		if err != nil {
-			klog.Errorf("Could not find ldap members for %s : %s", c.ldap.AdminGroupBase, err)
+// LocalSyncClusterMembers computes the cluster members from every configured
+// role, each user getting the role with the highest priority
+func (c *Controller) LocalSyncClusterMembers() error {
+	var errs []error
+
+	for _, role := range c.ldap.ClusterRoles {
+		if len(role.GroupDNs) == 0 {
+			klog.Warningf("Ignored role %v has it was not specified in configuration", role.Name)
+			continue
+		}
+
+		for _, groupDN := range role.GroupDNs {
+			users, err := c.ldap.Search(groupDN)
+			if err != nil {
+				klog.Errorf("Could not find ldap members for %s : %s", groupDN, err)
+				c.failedRoles[role.Name] = err
+				errs = append(errs, fmt.Errorf("keeping previous %s members : %w", role.Name, err))
+				continue
+			}
+			c.synchronizeClusterMembersByRole(users, role, groupDN)
 		}
-		c.synchronizeClusterMembersByRole(adminsUsers, utils.AdminRole)
-	} else {
-		klog.Warningf("Ignored role %v has it was not specified in configuration", utils.AdminRole)
 	}
 
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
 func (c *Controller) indexOfClusterMember(user ldap.User) int {
+	name := memberName(user)
 	for i := 0; i < len(c.clusterMembers); i++ {
-		if c.clusterMembers[i].Mail == user.Mail {
+		if c.clusterMembers[i].Name == name {
 			return i
 		}
 	}
 	return -1
 }
 
-func (c *Controller) synchronizeClusterMembersByRole(members ldap.Users, role utils.ClusterRole) {
+func (c *Controller) synchronizeClusterMembersByRole(members ldap.Users, role utils.Role, groupDN string) {
+	grant := v2.ClusterMemberRole{Name: role.Name, GroupDN: groupDN}
 	for _, member := range members {
 		userIndex := c.indexOfClusterMember(member)
 		if userIndex == -1 {
-			c.clusterMembers = append(c.clusterMembers, c.templateClusterMember(member, role))
+			clusterMember := c.templateClusterMember(member, role)
+			clusterMember.Spec.Roles = []v2.ClusterMemberRole{grant}
+			c.clusterMembers = append(c.clusterMembers, clusterMember)
 		} else {
+			spec := &c.clusterMembers[userIndex].Spec
+			spec.Roles = appendRole(spec.Roles, grant)
 			// Change user Role only if current has less privileges
-			_, userRole := utils.GetClusterRole(c.clusterMembers[userIndex].Role)
-			if userRole < role {
-				c.clusterMembers[userIndex].Role = role.String()
+			userRole, _ := c.ldap.ClusterRoles.Get(spec.Role)
+			if userRole.Priority < role.Priority {
+				spec.Role = role.Name
 			}
 		}
 	}
 }
 
-func (c *Controller) templateClusterMember(member ldap.User, role utils.ClusterRole) *v1.ClusterMember {
-	return &v1.ClusterMember{
+func appendRole(roles []v2.ClusterMemberRole, grant v2.ClusterMemberRole) []v2.ClusterMemberRole {
+	for _, role := range roles {
+		if role == grant {
+			return roles
+		}
+	}
+	return append(roles, grant)
+}
+
+// failedRole returns the lookup error of a role held by member during the
+// current sync, directly or through one of its grants
+func (c *Controller) failedRole(member *v2.ClusterMember) error {
+	if err := c.failedRoles[member.Spec.Role]; err != nil {
+		return err
+	}
+	for _, role := range member.Spec.Roles {
+		if err := c.failedRoles[role.Name]; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memberName is the name of the ClusterMember and ProjectMember objects of a user
+func memberName(user ldap.User) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(user.ID)))
+}
+
+func (c *Controller) templateClusterMember(member ldap.User, role utils.Role) *v2.ClusterMember {
+	return &v2.ClusterMember{
 		TypeMeta: metav1.TypeMeta{},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: fmt.Sprintf("%x", md5.Sum([]byte(member.ID))),
+			Name: memberName(member),
+		},
+		Spec: v2.ClusterMemberSpec{
+			UID:         member.ID,
+			Dn:          member.Dn,
+			Username:    member.Username,
+			Mail:        member.Mail,
+			DisplayName: member.DisplayName,
+			Attributes:  member.Attributes,
+			Role:        role.Name,
 		},
-		UID:      member.ID,
-		Dn:       member.Dn,
-		Username: member.Username,
-		Mail:     member.Mail,
-		Role:     role.String(),
 	}
 }
 
-func (c *Controller) createProjectMembers(namespace string, members []*v1.ProjectMember) {
+// syncProjectMembers applies the computed members of a namespace: new members
+// are created, changed fields patched and members who left the LDAP group
+// deleted. Unchanged members are never written.
+func (c *Controller) syncProjectMembers(namespace string, members []*v2.ProjectMember) error {
+	existingMembers, err := c.projectMembersLister.ProjectMembers(namespace).List(utils.DefaultLabelSelector())
+	if err != nil {
+		klog.Errorf("Could not list members of project %s : %s", namespace, err)
+		return err
+	}
+
+	countByRole := map[string]int{}
+	for _, member := range members {
+		countByRole[member.Spec.Role]++
+	}
+	metrics.SetProjectMembers(namespace, countByRole)
+
+	existing := make(map[string]*v2.ProjectMember, len(existingMembers))
+	for _, member := range existingMembers {
+		existing[member.Name] = member
+	}
+
 	for _, member := range members {
-		_, err := c.membersclientset.CagipV1().ProjectMembers(namespace).Get(context.TODO(), member.Username, metav1.GetOptions{})
-		if errors.IsNotFound(err) {
-			_, err := c.membersclientset.CagipV1().ProjectMembers(namespace).Create(context.TODO(), member, metav1.CreateOptions{})
+		current, found := existing[member.Name]
+		delete(existing, member.Name)
+
+		if !found {
+			created, err := c.membersclientset.CagipV2().ProjectMembers(namespace).Create(context.TODO(), member, metav1.CreateOptions{})
 			if err != nil {
-				klog.Errorf("Could not create ProjectMember %s : %s", member.Username, err)
+				klog.Errorf("Could not create ProjectMember %s : %s", member.Spec.Username, err)
+				continue
 			}
+			metrics.MemberChanged("ProjectMember", "create")
+			c.projectEvent(namespace, corev1.EventTypeNormal, ReasonMemberAdded, "%s was granted role %s", created.Spec.Username, created.Spec.Role)
+			c.updateProjectMemberStatus(created, syncedStatus(created.Status, created.Generation, member.Status.ObservedSourceDN, member.Status.SourceGroups))
+			c.syncProjectMemberBinding(created)
+			continue
+		}
+
+		patch := projectMemberPatch(current, member)
+		if len(patch) == 0 {
+			c.updateProjectMemberStatus(current, syncedStatus(current.Status, current.Generation, member.Status.ObservedSourceDN, member.Status.SourceGroups))
+			c.syncProjectMemberBinding(current)
+			continue
 		}
+
+		data, err := json.Marshal(map[string]interface{}{"spec": patch})
+		if err != nil {
+			klog.Errorf("Could not build patch for ProjectMember %s : %s", member.Spec.Username, err)
+			continue
+		}
+		patched, err := c.membersclientset.CagipV2().ProjectMembers(namespace).Patch(context.TODO(), member.Name, types.MergePatchType, data, metav1.PatchOptions{})
+		if err != nil {
+			klog.Errorf("Could not patch ProjectMember %s : %s", member.Spec.Username, err)
+			continue
+		}
+		metrics.MemberChanged("ProjectMember", "update")
+		c.updateProjectMemberStatus(patched, syncedStatus(patched.Status, patched.Generation, member.Status.ObservedSourceDN, member.Status.SourceGroups))
+		c.syncProjectMemberBinding(patched)
+	}
+
+	if err := c.allowDeletions("project "+namespace, len(existing), len(existingMembers)); err != nil {
+		c.projectEvent(namespace, corev1.EventTypeWarning, ReasonDeletionsRefused, "Keeping members no longer found in LDAP, %s, see --force-deletions", err)
+		return err
 	}
 
+	for name, member := range existing {
+		err := c.membersclientset.CagipV2().ProjectMembers(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("Could not delete ProjectMember %s : %s", member.Spec.Username, err)
+			continue
+		}
+		metrics.MemberChanged("ProjectMember", "delete")
+		c.projectEvent(namespace, corev1.EventTypeNormal, ReasonMemberRemoved, "%s lost role %s", member.Spec.Username, member.Spec.Role)
+	}
+	return nil
 }
 
-func (c *Controller) templateProjectMember(project *kubiv1.Project, user ldap.User) *v1.ProjectMember {
-	return &v1.ProjectMember{
+// projectMemberPatch returns the spec fields of the merge patch turning current
+// into desired, empty when both are equal
+func projectMemberPatch(current, desired *v2.ProjectMember) map[string]interface{} {
+	patch := map[string]interface{}{}
+	if current.Spec.UID != desired.Spec.UID {
+		patch["uid"] = desired.Spec.UID
+	}
+	if current.Spec.Dn != desired.Spec.Dn {
+		patch["dn"] = desired.Spec.Dn
+	}
+	if current.Spec.Username != desired.Spec.Username {
+		patch["username"] = desired.Spec.Username
+	}
+	if current.Spec.Mail != desired.Spec.Mail {
+		patch["mail"] = desired.Spec.Mail
+	}
+	if current.Spec.DisplayName != desired.Spec.DisplayName {
+		patch["displayName"] = desired.Spec.DisplayName
+	}
+	if current.Spec.Role != desired.Spec.Role {
+		patch["role"] = desired.Spec.Role
+	}
+	if !equality.Semantic.DeepEqual(current.Spec.Attributes, desired.Spec.Attributes) {
+		// A merge patch keeps the keys it does not mention, removed ones are set to null
+		attributes := map[string]interface{}{}
+		for key := range current.Spec.Attributes {
+			attributes[key] = nil
+		}
+		for key, value := range desired.Spec.Attributes {
+			attributes[key] = value
+		}
+		patch["attributes"] = attributes
+	}
+	return patch
+}
+
+func (c *Controller) templateProjectMember(project *kubiv1.Project, user ldap.User, role string, groupDN string) *v2.ProjectMember {
+	return &v2.ProjectMember{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%x", md5.Sum([]byte(user.ID))),
+			Name:      memberName(user),
 			Namespace: project.Name,
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(project, kubiv1.SchemeGroupVersion.WithKind("Project")),
 			},
 		},
-		UID:      user.ID,
-		Dn:       user.Dn,
-		Username: user.Username,
-		Mail:     user.Mail,
-	}
-}
-
-func (c *Controller) templateProjectMembers(project *kubiv1.Project, users []ldap.User) (members []*v1.ProjectMember) {
-	for _, user := range users {
-		member := c.templateProjectMember(project, user)
-		members = append(members, member)
-	}
-	return
-}
-
-func (c *Controller) clearProjectsMembers() {
-	projects, err := c.projectclientset.CagipV1().Projects().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		klog.Errorf("Could not list projects")
-	}
-	for _, project := range projects.Items {
-		err = c.membersclientset.CagipV1().ProjectMembers(project.Name).DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{})
-		if err != nil {
-			klog.Errorf("Could not remove members from project %s: %v", project.Name, err)
-		}
+		Spec: v2.ProjectMemberSpec{
+			UID:         user.ID,
+			Dn:          user.Dn,
+			Username:    user.Username,
+			Mail:        user.Mail,
+			DisplayName: user.DisplayName,
+			Attributes:  user.Attributes,
+			Role:        role,
+		},
+		// Desired source of the membership, applied to the status once synced
+		Status: v2.MemberStatus{
+			ObservedSourceDN: project.Spec.SourceDN,
+			SourceGroups:     []string{groupDN},
+		},
 	}
 }
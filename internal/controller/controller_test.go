@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	membersfake "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/fake"
+	memberslisters "github.com/ca-gip/kubi-members/pkg/generated/listers/cagip/v2"
+	kubiv1 "github.com/ca-gip/kubi/pkg/apis/cagip/v1"
+	projectlisters "github.com/ca-gip/kubi/pkg/generated/listers/cagip/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// newTestController returns a controller whose listers hold objects, writes
+// going to the returned fake clientset
+func newTestController(t *testing.T, options Options, objects ...runtime.Object) (*Controller, *membersfake.Clientset, *record.FakeRecorder) {
+	t.Helper()
+	clusterMembers := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	projectMembers := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	projects := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, object := range objects {
+		var err error
+		switch object.(type) {
+		case *v2.ClusterMember:
+			err = clusterMembers.Add(object)
+		case *v2.ProjectMember:
+			err = projectMembers.Add(object)
+		case *kubiv1.Project:
+			err = projects.Add(object)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var memberObjects []runtime.Object
+	for _, object := range objects {
+		if _, ok := object.(*kubiv1.Project); !ok {
+			memberObjects = append(memberObjects, object)
+		}
+	}
+	client := membersfake.NewSimpleClientset(memberObjects...)
+	recorder := record.NewFakeRecorder(100)
+	c := &Controller{
+		membersclientset:     client,
+		projectsLister:       projectlisters.NewProjectLister(projects),
+		clusterMembersLister: memberslisters.NewClusterMemberLister(clusterMembers),
+		projectMembersLister: memberslisters.NewProjectMemberLister(projectMembers),
+		failedRoles:          map[string]error{},
+		failedProjects:       map[string]error{},
+		recorder:             recorder,
+		options:              options,
+	}
+	return c, client, recorder
+}
+
+// writes returns the verb and name of the writes of the members, status
+// updates excluded
+func writes(client *membersfake.Clientset) []string {
+	var verbs []string
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "list" || action.GetVerb() == "watch" || action.GetVerb() == "get" || action.GetSubresource() == "status" {
+			continue
+		}
+		name := ""
+		switch action := action.(type) {
+		case k8stesting.CreateAction:
+			name = action.GetObject().(interface{ GetName() string }).GetName()
+		case k8stesting.UpdateAction:
+			name = action.GetObject().(interface{ GetName() string }).GetName()
+		case k8stesting.DeleteAction:
+			name = action.GetName()
+		case k8stesting.PatchAction:
+			name = action.GetName()
+		}
+		verbs = append(verbs, action.GetVerb()+" "+name)
+	}
+	return verbs
+}
+
+// events drains the events recorded by recorder
+func events(recorder *record.FakeRecorder) []string {
+	var recorded []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			recorded = append(recorded, event)
+		default:
+			return recorded
+		}
+	}
+}
+
+func TestSyncClusterMembers(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []*v2.ClusterMember
+		desired  []*v2.ClusterMember
+		writes   []string
+		events   []string
+	}{
+		{
+			name:    "create",
+			desired: []*v2.ClusterMember{clusterMember("alice", "admin")},
+			writes:  []string{"create alice"},
+		},
+		{
+			name:     "no-op",
+			existing: []*v2.ClusterMember{clusterMember("alice", "admin")},
+			desired:  []*v2.ClusterMember{clusterMember("alice", "admin")},
+		},
+		{
+			name:     "role change",
+			existing: []*v2.ClusterMember{clusterMember("alice", "ops")},
+			desired:  []*v2.ClusterMember{clusterMember("alice", "admin")},
+			writes:   []string{"update alice"},
+			events:   []string{"Normal RoleChanged Role of alice changed from ops to admin"},
+		},
+		{
+			name:     "delete",
+			existing: []*v2.ClusterMember{clusterMember("alice", "admin"), clusterMember("bob", "ops")},
+			desired:  []*v2.ClusterMember{clusterMember("alice", "admin")},
+			writes:   []string{"delete bob"},
+		},
+		{
+			name:     "create, update and delete",
+			existing: []*v2.ClusterMember{clusterMember("alice", "admin"), clusterMember("bob", "ops"), clusterMember("carol", "ops")},
+			desired:  []*v2.ClusterMember{clusterMember("alice", "admin"), clusterMember("bob", "admin", "ops"), clusterMember("dave", "ops")},
+			writes:   []string{"create dave", "delete carol", "update bob"},
+			events:   []string{"Normal RoleChanged Role of bob changed from ops to admin"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var objects []runtime.Object
+			for _, member := range test.existing {
+				objects = append(objects, member)
+			}
+			c, client, recorder := newTestController(t, Options{MaxDeletionPercent: 100}, objects...)
+			c.clusterMembers = test.desired
+
+			if err := c.SyncClusterMembers(); err != nil {
+				t.Fatal(err)
+			}
+			got := writes(client)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.writes) {
+				t.Errorf("writes %v, want %v", got, test.writes)
+			}
+			if got := events(recorder); !reflect.DeepEqual(got, test.events) {
+				t.Errorf("events %v, want %v", got, test.events)
+			}
+		})
+	}
+}
+
+func projectMember(namespace, name, role string, attributes map[string]string) *v2.ProjectMember {
+	return &v2.ProjectMember{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       v2.ProjectMemberSpec{UID: name, Username: name, Mail: name + "@example.org", Role: role, Attributes: attributes},
+	}
+}
+
+func TestProjectMemberPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *v2.ProjectMember
+		desired *v2.ProjectMember
+		patch   string
+	}{
+		{
+			name:    "unchanged",
+			current: projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops"}),
+			desired: projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops"}),
+			patch:   `{}`,
+		},
+		{
+			name:    "no attributes",
+			current: projectMember("team-dev", "alice", "admin", nil),
+			desired: projectMember("team-dev", "alice", "admin", map[string]string{}),
+			patch:   `{}`,
+		},
+		{
+			name:    "role",
+			current: projectMember("team-dev", "alice", "view", nil),
+			desired: projectMember("team-dev", "alice", "admin", nil),
+			patch:   `{"role":"admin"}`,
+		},
+		{
+			name:    "attribute added",
+			current: projectMember("team-dev", "alice", "admin", nil),
+			desired: projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops"}),
+			patch:   `{"attributes":{"department":"ops"}}`,
+		},
+		{
+			name:    "attribute removed",
+			current: projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops", "title": "SRE"}),
+			desired: projectMember("team-dev", "alice", "admin", map[string]string{"title": "SRE"}),
+			patch:   `{"attributes":{"department":null,"title":"SRE"}}`,
+		},
+		{
+			name:    "every attribute removed",
+			current: projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops"}),
+			desired: projectMember("team-dev", "alice", "admin", nil),
+			patch:   `{"attributes":{"department":null}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(projectMemberPatch(test.current, test.desired))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.patch {
+				t.Errorf("patch %s, want %s", data, test.patch)
+			}
+		})
+	}
+}
+
+func TestSyncProjectMembers(t *testing.T) {
+	synced := projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops"})
+	synced.Status = syncedStatus(synced.Status, synced.Generation, "", nil)
+
+	t.Run("unchanged members are not written", func(t *testing.T) {
+		c, client, recorder := newTestController(t, Options{}, synced.DeepCopy())
+		if err := c.syncProjectMembers("team-dev", []*v2.ProjectMember{projectMember("team-dev", "alice", "admin", map[string]string{"department": "ops"})}); err != nil {
+			t.Fatal(err)
+		}
+		for _, action := range client.Actions() {
+			if action.GetVerb() != "list" && action.GetVerb() != "watch" {
+				t.Errorf("unexpected %s of %s %s", action.GetVerb(), action.GetResource().Resource, action.GetSubresource())
+			}
+		}
+		if e := events(recorder); len(e) != 0 {
+			t.Errorf("unexpected events %v", e)
+		}
+	})
+
+	t.Run("removed attributes are deleted", func(t *testing.T) {
+		c, client, _ := newTestController(t, Options{}, synced.DeepCopy())
+		if err := c.syncProjectMembers("team-dev", []*v2.ProjectMember{projectMember("team-dev", "alice", "admin", nil)}); err != nil {
+			t.Fatal(err)
+		}
+		if w := writes(client); !reflect.DeepEqual(w, []string{"patch alice"}) {
+			t.Fatalf("writes %v, want a single patch", w)
+		}
+		patched, err := client.CagipV2().ProjectMembers("team-dev").Get(context.TODO(), "alice", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(patched.Spec.Attributes) != 0 {
+			t.Errorf("attributes %v left after the patch", patched.Spec.Attributes)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/ca-gip/kubi-members/internal/ldap"
+	"github.com/ca-gip/kubi-members/internal/utils"
+	membersscheme "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/scheme"
+	projectscheme "github.com/ca-gip/kubi/pkg/generated/clientset/versioned/scheme"
+	corev1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// Reasons of the Events recorded on Projects and ClusterMembers
+const (
+	ReasonMemberAdded      = "MemberAdded"
+	ReasonMemberRemoved    = "MemberRemoved"
+	ReasonSourceDNNotFound = "SourceDNNotFound"
+	ReasonLDAPSearchFailed = "LDAPSearchFailed"
+	ReasonRoleChanged      = "RoleChanged"
+	ReasonDeletionsRefused = "DeletionsRefused"
+)
+
+// startEventRecorder sets the recorder of the Events sent to kubeClient
+func (c *Controller) startEventRecorder(kubeClient kubernetes.Interface) {
+	// Events reference Projects and members, their types must be known to the scheme
+	utilruntime.Must(projectscheme.AddToScheme(scheme.Scheme))
+	utilruntime.Must(membersscheme.AddToScheme(scheme.Scheme))
+
+	c.broadcaster = record.NewBroadcaster()
+	c.broadcaster.StartStructuredLogging(4)
+	c.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	c.recorder = c.broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: utils.ManagedBy})
+}
+
+// ShutdownEvents stops the event broadcaster. It does not wait for the Events
+// being sent, so those recorded just before the process exits, as with --once,
+// may be lost.
+func (c *Controller) ShutdownEvents() {
+	c.broadcaster.Shutdown()
+}
+
+// projectEvent records an Event on the Project of namespace
+func (c *Controller) projectEvent(namespace string, eventType string, reason string, messageFmt string, args ...interface{}) {
+	project, err := c.projectsLister.Get(namespace)
+	if err != nil {
+		klog.V(4).Infof("Could not record %s event on project %s : %s", reason, namespace, err)
+		return
+	}
+	c.recorder.Eventf(project, eventType, reason, messageFmt, args...)
+}
+
+// searchFailedEvent records why the members of the project of namespace could not be computed
+func (c *Controller) searchFailedEvent(namespace string, err error) {
+	if errors.Is(err, ldap.ErrGroupNotFound) {
+		c.projectEvent(namespace, corev1.EventTypeWarning, ReasonSourceDNNotFound, "Keeping previous members, %s", err)
+		return
+	}
+	c.projectEvent(namespace, corev1.EventTypeWarning, ReasonLDAPSearchFailed, "Keeping previous members, LDAP search failed : %s", err)
+}
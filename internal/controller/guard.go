@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ErrDeletionsRefused is wrapped by the errors of the syncs whose deletions
+// were refused by the deletion guard
+var ErrDeletionsRefused = errors.New("deletions refused")
+
+// allowDeletions refuses to delete more than MaxDeletionPercent of the existing
+// members of scope in a single sync, such a drop is more likely caused by an
+// LDAP outage or misconfiguration than by actual departures
+func (c *Controller) allowDeletions(scope string, deletions int, existing int) error {
+	if c.deletionsAllowed(deletions, existing) {
+		return nil
+	}
+	return fmt.Errorf("%w : %d of the %d members of %s would be deleted, more than the %d%% allowed in a single sync",
+		ErrDeletionsRefused, deletions, existing, scope, c.options.MaxDeletionPercent)
+}
+
+func (c *Controller) deletionsAllowed(deletions int, existing int) bool {
+	if c.options.ForceDeletions || deletions == 0 || deletions < c.options.DeletionGuardMin {
+		return true
+	}
+	return deletions*100 <= existing*c.options.MaxDeletionPercent
+}
+
+// deletionsRefused tells whether err only reports refused deletions, which a
+// retry before the next resync would refuse again
+func deletionsRefused(err error) bool {
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		for _, err := range utilerrors.Flatten(agg).Errors() {
+			if !errors.Is(err, ErrDeletionsRefused) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, ErrDeletionsRefused)
+}
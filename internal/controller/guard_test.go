@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestDeletionsAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   Options
+		deletions int
+		existing  int
+		allowed   bool
+	}{
+		{"no deletion", Options{MaxDeletionPercent: 50, DeletionGuardMin: 5}, 0, 10, true},
+		{"below the floor", Options{MaxDeletionPercent: 50, DeletionGuardMin: 5}, 4, 4, true},
+		{"at the floor under the threshold", Options{MaxDeletionPercent: 50, DeletionGuardMin: 5}, 5, 10, true},
+		{"at the floor over the threshold", Options{MaxDeletionPercent: 50, DeletionGuardMin: 5}, 5, 9, false},
+		{"every member", Options{MaxDeletionPercent: 50, DeletionGuardMin: 5}, 20, 20, false},
+		{"no floor", Options{MaxDeletionPercent: 50}, 1, 1, false},
+		{"no floor under the threshold", Options{MaxDeletionPercent: 50}, 1, 2, true},
+		{"no limit", Options{MaxDeletionPercent: 100, DeletionGuardMin: 5}, 20, 20, true},
+		{"forced", Options{MaxDeletionPercent: 50, DeletionGuardMin: 5, ForceDeletions: true}, 20, 20, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Controller{options: test.options}
+			if allowed := c.deletionsAllowed(test.deletions, test.existing); allowed != test.allowed {
+				t.Errorf("deletionsAllowed(%d, %d) = %t, want %t", test.deletions, test.existing, allowed, test.allowed)
+			}
+			err := c.allowDeletions("project team-dev", test.deletions, test.existing)
+			if (err == nil) != test.allowed {
+				t.Errorf("allowDeletions(%d, %d) = %v, want allowed %t", test.deletions, test.existing, err, test.allowed)
+			}
+			if err != nil && (!errors.Is(err, ErrDeletionsRefused) || !strings.Contains(err.Error(), "project team-dev")) {
+				t.Errorf("unexpected error %q", err)
+			}
+		})
+	}
+}
+
+func TestDeletionsRefused(t *testing.T) {
+	refused := fmt.Errorf("%w : 6 of the 6 members", ErrDeletionsRefused)
+	other := errors.New("ldap unavailable")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", refused, true},
+		{"other", other, false},
+		{"aggregate of refused", utilerrors.NewAggregate([]error{refused, nil}), true},
+		{"nested aggregate", utilerrors.NewAggregate([]error{utilerrors.NewAggregate([]error{refused}), refused}), true},
+		{"aggregate with another error", utilerrors.NewAggregate([]error{refused, other}), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := deletionsRefused(test.err); got != test.want {
+				t.Errorf("deletionsRefused(%v) = %t, want %t", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func clusterMember(name string, roles ...string) *v2.ClusterMember {
+	member := &v2.ClusterMember{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v2.ClusterMemberSpec{UID: name, Username: name},
+	}
+	for _, role := range roles {
+		member.Spec.Roles = append(member.Spec.Roles, v2.ClusterMemberRole{Name: role, GroupDN: "cn=" + role})
+	}
+	if len(roles) > 0 {
+		member.Spec.Role = roles[0]
+	}
+	return member
+}
+
+func TestSyncClusterMembersDeletionGuard(t *testing.T) {
+	var existing []runtime.Object
+	for i := 0; i < 6; i++ {
+		role := "ops"
+		if i%2 == 0 {
+			role = "admin"
+		}
+		existing = append(existing, clusterMember(fmt.Sprintf("user%d", i), role))
+	}
+
+	t.Run("refused", func(t *testing.T) {
+		c, client, recorder := newTestController(t, Options{MaxDeletionPercent: 50, DeletionGuardMin: 2}, existing...)
+		err := c.SyncClusterMembers()
+		if !errors.Is(err, ErrDeletionsRefused) {
+			t.Fatalf("expected refused deletions, got %v", err)
+		}
+		if w := writes(client); len(w) != 0 {
+			t.Errorf("unexpected writes %v", w)
+		}
+		if e := events(recorder); len(e) != 6 || !strings.HasPrefix(e[0], "Warning DeletionsRefused") {
+			t.Errorf("unexpected events %v", e)
+		}
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		c, client, _ := newTestController(t, Options{MaxDeletionPercent: 50, DeletionGuardMin: 2, ForceDeletions: true}, existing...)
+		if err := c.SyncClusterMembers(); err != nil {
+			t.Fatal(err)
+		}
+		if w := writes(client); len(w) != 6 {
+			t.Errorf("expected 6 deletions, got %v", w)
+		}
+	})
+
+	t.Run("failed role kept", func(t *testing.T) {
+		// The admins are kept as their role lookup failed, deleting the 3 ops
+		// members only is within the guard
+		c, client, _ := newTestController(t, Options{MaxDeletionPercent: 50, DeletionGuardMin: 2}, existing...)
+		c.failedRoles["admin"] = errors.New("ldap unavailable")
+		if err := c.SyncClusterMembers(); err != nil {
+			t.Fatal(err)
+		}
+		w := writes(client)
+		sort.Strings(w)
+		want := []string{"delete user1", "delete user3", "delete user5"}
+		if strings.Join(w, ",") != strings.Join(want, ",") {
+			t.Errorf("writes %v, want %v", w, want)
+		}
+	})
+}
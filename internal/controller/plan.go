@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ca-gip/kubi-members/internal/utils"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ClusterScope is the scope of the changes to cluster members in a plan
+const ClusterScope = "(cluster)"
+
+// Actions of the changes of a plan
+const (
+	ActionAdd    = "add"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+// Change is a member that a sync would create, update or delete
+type Change struct {
+	Action       string `json:"action"`
+	Name         string `json:"name"`
+	Username     string `json:"username"`
+	Role         string `json:"role,omitempty"`
+	PreviousRole string `json:"previousRole,omitempty"`
+}
+
+// ScopePlan holds the changes to the members of a namespace, or of the cluster
+type ScopePlan struct {
+	Scope   string   `json:"scope"`
+	Changes []Change `json:"changes"`
+	// Blocked is set when the removals exceed the deletion guard and would be refused
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+// Plan lists the changes a sync would apply, Errors holding the lookups that
+// failed and whose members would be kept
+type Plan struct {
+	Scopes []ScopePlan `json:"scopes"`
+	Errors []string    `json:"errors,omitempty"`
+}
+
+// HasDrift reports whether the members differ from LDAP
+func (p Plan) HasDrift() bool {
+	return len(p.Scopes) > 0
+}
+
+// Plan computes the members from LDAP and compares them with the existing
+// ones, without writing anything
+func (c *Controller) Plan() (plan Plan, err error) {
+	c.ldap.ResetCache()
+
+	c.clusterMembers = []*v2.ClusterMember{}
+	c.projectsMembers = make(map[string][]*v2.ProjectMember)
+	c.failedRoles = make(map[string]error)
+	c.failedProjects = make(map[string]error)
+
+	// Failed lookups are only reported, members are neither marked nor evented
+	clusterErr := c.LocalSyncClusterMembers()
+	projectsErr := c.LocalSyncProjectsMembers()
+	plan.Errors = errorMessages(clusterErr, projectsErr)
+
+	clusterPlan, err := c.planClusterMembers()
+	if err != nil {
+		return
+	}
+	if len(clusterPlan.Changes) > 0 {
+		plan.Scopes = append(plan.Scopes, clusterPlan)
+	}
+
+	namespaces := make([]string, 0, len(c.projectsMembers))
+	for namespace := range c.projectsMembers {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		projectPlan, err := c.planProjectMembers(namespace, c.projectsMembers[namespace])
+		if err != nil {
+			return plan, err
+		}
+		if len(projectPlan.Changes) > 0 {
+			plan.Scopes = append(plan.Scopes, projectPlan)
+		}
+	}
+	return
+}
+
+// errorMessages returns the message of each of errs, aggregates being flattened
+func errorMessages(errs ...error) []string {
+	var messages []string
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			for _, err := range utilerrors.Flatten(agg).Errors() {
+				messages = append(messages, err.Error())
+			}
+			continue
+		}
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func (c *Controller) planClusterMembers() (ScopePlan, error) {
+	plan := ScopePlan{Scope: ClusterScope}
+	existingMembers, err := c.clusterMembersLister.List(utils.DefaultLabelSelector())
+	if err != nil {
+		return plan, err
+	}
+
+	existing := make(map[string]*v2.ClusterMember, len(existingMembers))
+	for _, member := range existingMembers {
+		existing[member.Name] = member
+	}
+
+	for _, member := range c.clusterMembers {
+		current, found := existing[member.Name]
+		delete(existing, member.Name)
+
+		switch {
+		case !found:
+			plan.Changes = append(plan.Changes, Change{Action: ActionAdd, Name: member.Name, Username: member.Spec.Username, Role: member.Spec.Role})
+		case c.failedRole(current) != nil || clusterMemberEqual(current, member):
+		default:
+			plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Name: member.Name, Username: member.Spec.Username, Role: member.Spec.Role, PreviousRole: current.Spec.Role})
+		}
+	}
+
+	removals := 0
+	for _, member := range existing {
+		if c.failedRole(member) != nil {
+			continue
+		}
+		removals++
+		plan.Changes = append(plan.Changes, Change{Action: ActionRemove, Name: member.Name, Username: member.Spec.Username, PreviousRole: member.Spec.Role})
+	}
+	plan.Blocked = !c.deletionsAllowed(removals, len(existingMembers))
+	sortChanges(plan.Changes)
+	return plan, nil
+}
+
+func (c *Controller) planProjectMembers(namespace string, members []*v2.ProjectMember) (ScopePlan, error) {
+	plan := ScopePlan{Scope: namespace}
+	existingMembers, err := c.projectMembersLister.ProjectMembers(namespace).List(utils.DefaultLabelSelector())
+	if err != nil {
+		return plan, err
+	}
+
+	existing := make(map[string]*v2.ProjectMember, len(existingMembers))
+	for _, member := range existingMembers {
+		existing[member.Name] = member
+	}
+
+	for _, member := range members {
+		current, found := existing[member.Name]
+		delete(existing, member.Name)
+
+		switch {
+		case !found:
+			plan.Changes = append(plan.Changes, Change{Action: ActionAdd, Name: member.Name, Username: member.Spec.Username, Role: member.Spec.Role})
+		case len(projectMemberPatch(current, member)) > 0:
+			plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Name: member.Name, Username: member.Spec.Username, Role: member.Spec.Role, PreviousRole: current.Spec.Role})
+		}
+	}
+
+	for _, member := range existing {
+		plan.Changes = append(plan.Changes, Change{Action: ActionRemove, Name: member.Name, Username: member.Spec.Username, PreviousRole: member.Spec.Role})
+	}
+	plan.Blocked = !c.deletionsAllowed(len(existing), len(existingMembers))
+	sortChanges(plan.Changes)
+	return plan, nil
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Action != changes[j].Action {
+			return changes[i].Action < changes[j].Action
+		}
+		return changes[i].Username < changes[j].Username
+	})
+}
+
+// WriteText writes the plan in a human readable form
+func (p Plan) WriteText(w io.Writer) {
+	symbols := map[string]string{ActionAdd: "+", ActionUpdate: "~", ActionRemove: "-"}
+	add, update, remove := 0, 0, 0
+
+	for _, scope := range p.Scopes {
+		fmt.Fprintf(w, "%s:\n", scope.Scope)
+		for _, change := range scope.Changes {
+			switch change.Action {
+			case ActionAdd:
+				add++
+				fmt.Fprintf(w, "  %s %s (%s)\n", symbols[change.Action], change.Username, change.Role)
+			case ActionUpdate:
+				update++
+				if change.PreviousRole != change.Role {
+					fmt.Fprintf(w, "  %s %s (%s -> %s)\n", symbols[change.Action], change.Username, change.PreviousRole, change.Role)
+				} else {
+					fmt.Fprintf(w, "  %s %s (attributes changed)\n", symbols[change.Action], change.Username)
+				}
+			case ActionRemove:
+				remove++
+				fmt.Fprintf(w, "  %s %s (%s)\n", symbols[change.Action], change.Username, change.PreviousRole)
+			}
+		}
+		if scope.Blocked {
+			fmt.Fprintf(w, "  ! removals exceed the deletion guard and would be refused\n")
+		}
+	}
+
+	for _, err := range p.Errors {
+		fmt.Fprintf(w, "error: %s\n", err)
+	}
+	if !p.HasDrift() {
+		fmt.Fprintln(w, "No changes, members are up to date.")
+		return
+	}
+	fmt.Fprintf(w, "Plan: %d to add, %d to update, %d to remove.\n", add, update, remove)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	kubiv1 "github.com/ca-gip/kubi/pkg/apis/cagip/v1"
+	projectlisters "github.com/ca-gip/kubi/pkg/generated/listers/cagip/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// failingProjectLister fails every List, as a lister whose cache is broken
+type failingProjectLister struct {
+	projectlisters.ProjectLister
+	err error
+}
+
+func (l failingProjectLister) List(labels.Selector) ([]*kubiv1.Project, error) {
+	return nil, l.err
+}
+
+func TestErrorMessages(t *testing.T) {
+	first, second, third := errors.New("first"), errors.New("second"), errors.New("third")
+	tests := []struct {
+		name string
+		errs []error
+		want []string
+	}{
+		{"none", []error{nil, nil}, nil},
+		{"plain error", []error{nil, first}, []string{"first"}},
+		{"aggregate", []error{utilerrors.NewAggregate([]error{first, second}), nil}, []string{"first", "second"}},
+		{"nested aggregate and plain error", []error{utilerrors.NewAggregate([]error{utilerrors.NewAggregate([]error{first}), second}), third}, []string{"first", "second", "third"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := errorMessages(test.errs...); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("errorMessages() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlanReportsProjectListErrors(t *testing.T) {
+	c, _, _ := newTestController(t, Options{})
+	c.projectsLister = failingProjectLister{err: errors.New("projects cache unavailable")}
+
+	err := c.LocalSyncProjectsMembers()
+	if err == nil {
+		t.Fatal("expected the list error")
+	}
+	if got := errorMessages(nil, err); !reflect.DeepEqual(got, []string{"projects cache unavailable"}) {
+		t.Errorf("plan errors %v, the list error is missing", got)
+	}
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/ca-gip/kubi-members/internal/utils"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// bindingName is the name of the binding generated for the member called name
+func bindingName(name string) string {
+	return utils.ManagedBy + "-" + name
+}
+
+// binding holds the fields compared between the current and the desired
+// RoleBinding or ClusterRoleBinding of a member
+type binding struct {
+	subjects []rbacv1.Subject
+	roleRef  rbacv1.RoleRef
+}
+
+// bindingWriter writes the RoleBinding or ClusterRoleBinding of a member
+type bindingWriter struct {
+	create func() error
+	update func(subjects []rbacv1.Subject) error
+	delete func() error
+}
+
+// desiredBinding returns the binding of subject to the ClusterRole mapped to
+// role by roles, nil when the role is not mapped or the subject is empty
+func desiredBinding(roles map[string]string, role string, subject string) *binding {
+	clusterRole, ok := roles[role]
+	if !ok || subject == "" {
+		return nil
+	}
+	return &binding{
+		subjects: []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: subject}},
+		roleRef:  rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole},
+	}
+}
+
+// applyBinding turns the current binding into the desired one, nil standing
+// for no binding
+func applyBinding(current, desired *binding, writer bindingWriter) error {
+	var err error
+	switch {
+	case current == nil && desired == nil:
+		return nil
+	case desired == nil:
+		err = writer.delete()
+	case current == nil:
+		err = writer.create()
+	case current.roleRef != desired.roleRef:
+		// The role of a binding cannot be changed, it is created again
+		err = writer.delete()
+		if err == nil || errors.IsNotFound(err) {
+			err = writer.create()
+		}
+	case !equality.Semantic.DeepEqual(current.subjects, desired.subjects):
+		err = writer.update(desired.subjects)
+	}
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// syncProjectMemberBinding binds the member to the ClusterRole mapped to its
+// role in its namespace. The RoleBinding is owned by the member and garbage
+// collected with it.
+func (c *Controller) syncProjectMemberBinding(member *v2.ProjectMember) {
+	if c.options.RBAC == nil {
+		return
+	}
+
+	name := bindingName(member.Name)
+	roleBinding, err := c.roleBindingsLister.RoleBindings(member.Namespace).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Could not get RoleBinding %s/%s : %s", member.Namespace, name, err)
+		return
+	}
+	var current *binding
+	if err == nil {
+		current = &binding{subjects: roleBinding.Subjects, roleRef: roleBinding.RoleRef}
+	}
+
+	subject := c.options.RBAC.Subject(member.Spec.UID, member.Spec.Dn, member.Spec.Username, member.Spec.Mail)
+	desired := desiredBinding(c.options.RBAC.ProjectRoles, member.Spec.Role, subject)
+
+	client := c.configmapclientset.RbacV1().RoleBindings(member.Namespace)
+	err = applyBinding(current, desired, bindingWriter{
+		create: func() error {
+			_, err := client.Create(context.TODO(), &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: member.Namespace,
+					Labels:    map[string]string{utils.ManagedByLabel: utils.ManagedBy},
+					OwnerReferences: []metav1.OwnerReference{
+						*metav1.NewControllerRef(member, v2.SchemeGroupVersion.WithKind("ProjectMember")),
+					},
+				},
+				Subjects: desired.subjects,
+				RoleRef:  desired.roleRef,
+			}, metav1.CreateOptions{})
+			return err
+		},
+		update: func(subjects []rbacv1.Subject) error {
+			updated := roleBinding.DeepCopy()
+			updated.Subjects = subjects
+			_, err := client.Update(context.TODO(), updated, metav1.UpdateOptions{})
+			return err
+		},
+		delete: func() error {
+			return client.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		},
+	})
+	if err != nil {
+		klog.Errorf("Could not apply RoleBinding %s/%s : %s", member.Namespace, name, err)
+	}
+}
+
+// syncClusterMemberBinding binds the member to the ClusterRole mapped to its
+// role cluster wide. The ClusterRoleBinding is owned by the member and garbage
+// collected with it.
+func (c *Controller) syncClusterMemberBinding(member *v2.ClusterMember) {
+	if c.options.RBAC == nil {
+		return
+	}
+
+	name := bindingName(member.Name)
+	clusterRoleBinding, err := c.clusterRoleBindingsLister.Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Could not get ClusterRoleBinding %s : %s", name, err)
+		return
+	}
+	var current *binding
+	if err == nil {
+		current = &binding{subjects: clusterRoleBinding.Subjects, roleRef: clusterRoleBinding.RoleRef}
+	}
+
+	subject := c.options.RBAC.Subject(member.Spec.UID, member.Spec.Dn, member.Spec.Username, member.Spec.Mail)
+	desired := desiredBinding(c.options.RBAC.ClusterRoles, member.Spec.Role, subject)
+
+	client := c.configmapclientset.RbacV1().ClusterRoleBindings()
+	err = applyBinding(current, desired, bindingWriter{
+		create: func() error {
+			_, err := client.Create(context.TODO(), &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{utils.ManagedByLabel: utils.ManagedBy},
+					OwnerReferences: []metav1.OwnerReference{
+						*metav1.NewControllerRef(member, v2.SchemeGroupVersion.WithKind("ClusterMember")),
+					},
+				},
+				Subjects: desired.subjects,
+				RoleRef:  desired.roleRef,
+			}, metav1.CreateOptions{})
+			return err
+		},
+		update: func(subjects []rbacv1.Subject) error {
+			updated := clusterRoleBinding.DeepCopy()
+			updated.Subjects = subjects
+			_, err := client.Update(context.TODO(), updated, metav1.UpdateOptions{})
+			return err
+		},
+		delete: func() error {
+			return client.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		},
+	})
+	if err != nil {
+		klog.Errorf("Could not apply ClusterRoleBinding %s : %s", name, err)
+	}
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/ca-gip/kubi-members/internal/utils"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// syncedRefresh is the age past which LastSyncedTime of an unchanged status is
+// refreshed, so that members are not all written on each resync
+const syncedRefresh = time.Hour
+
+// syncedStatus returns status updated for a membership just confirmed by LDAP.
+// LastSyncedTime is only moved when the rest of the status changed or when it
+// is older than syncedRefresh.
+func syncedStatus(status v2.MemberStatus, generation int64, observedSourceDN string, sourceGroups []string) v2.MemberStatus {
+	updated := *status.DeepCopy()
+	updated.ObservedSourceDN = observedSourceDN
+	updated.SourceGroups = sourceGroups
+
+	meta.SetStatusCondition(&updated.Conditions, metav1.Condition{
+		Type:               v2.ConditionSynced,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "MemberFound",
+		Message:            "Membership confirmed by LDAP",
+	})
+	meta.SetStatusCondition(&updated.Conditions, metav1.Condition{
+		Type:               v2.ConditionSourceUnavailable,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "LDAPSearchSucceeded",
+		Message:            "Source groups were read from LDAP",
+	})
+
+	if status.LastSyncedTime == nil || time.Since(status.LastSyncedTime.Time) >= syncedRefresh || !equality.Semantic.DeepEqual(status, updated) {
+		now := metav1.Now()
+		updated.LastSyncedTime = &now
+	}
+	return updated
+}
+
+// unavailableStatus returns status updated for a membership kept as is because
+// the LDAP lookup of its source groups failed
+func unavailableStatus(status v2.MemberStatus, generation int64, cause error) v2.MemberStatus {
+	updated := *status.DeepCopy()
+
+	meta.SetStatusCondition(&updated.Conditions, metav1.Condition{
+		Type:               v2.ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "LDAPSearchFailed",
+		Message:            cause.Error(),
+	})
+	meta.SetStatusCondition(&updated.Conditions, metav1.Condition{
+		Type:               v2.ConditionSourceUnavailable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "LDAPSearchFailed",
+		Message:            cause.Error(),
+	})
+	return updated
+}
+
+func (c *Controller) updateProjectMemberStatus(member *v2.ProjectMember, status v2.MemberStatus) {
+	if equality.Semantic.DeepEqual(member.Status, status) {
+		return
+	}
+
+	updated := member.DeepCopy()
+	updated.Status = status
+	_, err := c.membersclientset.CagipV2().ProjectMembers(member.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Could not update status of ProjectMember %s : %s", member.Spec.Username, err)
+	}
+}
+
+func (c *Controller) updateClusterMemberStatus(member *v2.ClusterMember, status v2.MemberStatus) {
+	if equality.Semantic.DeepEqual(member.Status, status) {
+		return
+	}
+
+	updated := member.DeepCopy()
+	updated.Status = status
+	_, err := c.membersclientset.CagipV2().ClusterMembers().UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Could not update status of cluster member %s : %s", member.Spec.Username, err)
+	}
+}
+
+// markProjectMembersUnavailable flags the existing members of namespace as not
+// confirmed, the lookup of the project group having failed
+func (c *Controller) markProjectMembersUnavailable(namespace string, cause error) {
+	members, err := c.projectMembersLister.ProjectMembers(namespace).List(utils.DefaultLabelSelector())
+	if err != nil {
+		klog.Errorf("Could not list members of project %s : %s", namespace, err)
+		return
+	}
+	for _, member := range members {
+		c.updateProjectMemberStatus(member, unavailableStatus(member.Status, member.Generation, cause))
+	}
+}
+
+// clusterMemberGroups returns the groups granting the roles of member
+func clusterMemberGroups(member *v2.ClusterMember) (groups []string) {
+	seen := map[string]bool{}
+	for _, role := range member.Spec.Roles {
+		if !seen[role.GroupDN] {
+			seen[role.GroupDN] = true
+			groups = append(groups, role.GroupDN)
+		}
+	}
+	return
+}
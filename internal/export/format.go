@@ -0,0 +1,123 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{"csv", "json", "markdown", "html"}
+
+// Write renders matrix to w in format
+func Write(w io.Writer, matrix Matrix, format string) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, matrix)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(matrix)
+	case "markdown", "md":
+		return writeMarkdown(w, matrix)
+	case "html":
+		return writeHTML(w, matrix)
+	default:
+		return fmt.Errorf("unknown format %q, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+func header(matrix Matrix) []string {
+	return append([]string{"uid", "username", "mail"}, matrix.Columns...)
+}
+
+func cells(matrix Matrix, row Row) []string {
+	record := []string{row.UID, row.Username, row.Mail}
+	for _, column := range matrix.Columns {
+		record = append(record, row.Roles[column])
+	}
+	return record
+}
+
+func writeCSV(w io.Writer, matrix Matrix) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header(matrix)); err != nil {
+		return err
+	}
+	for _, row := range matrix.Rows {
+		if err := writer.Write(cells(matrix, row)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeMarkdown(w io.Writer, matrix Matrix) error {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	line := func(values []string) string {
+		for i, value := range values {
+			values[i] = escape.Replace(value)
+		}
+		return "| " + strings.Join(values, " | ") + " |\n"
+	}
+
+	columns := header(matrix)
+	separator := make([]string, len(columns))
+	for i := range separator {
+		separator[i] = "---"
+	}
+
+	var b strings.Builder
+	b.WriteString(line(columns))
+	b.WriteString(line(separator))
+	for _, row := range matrix.Rows {
+		b.WriteString(line(cells(matrix, row)))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Access matrix</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f0f0f0; position: sticky; top: 0; }
+tr:nth-child(even) td { background: #fafafa; }
+td.role { font-weight: bold; color: #0b5394; }
+</style>
+</head>
+<body>
+<h1>Access matrix</h1>
+<p>Generated {{.Generated}}, {{len .Matrix.Rows}} users, {{len .Matrix.Columns}} scopes.</p>
+<table>
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range $i, $cell := .}}<td{{if and (ge $i 3) $cell}} class="role"{{end}}>{{$cell}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, matrix Matrix) error {
+	rows := make([][]string, 0, len(matrix.Rows))
+	for _, row := range matrix.Rows {
+		rows = append(rows, cells(matrix, row))
+	}
+	return htmlReport.Execute(w, map[string]interface{}{
+		"Generated": time.Now().UTC().Format(time.RFC3339),
+		"Matrix":    matrix,
+		"Header":    header(matrix),
+		"Rows":      rows,
+	})
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testMatrix() Matrix {
+	return Matrix{
+		Columns: []string{"payments-dev", ClusterColumn},
+		Rows: []Row{
+			{UID: "u1", Username: "asmith", Mail: "asmith@example.com", Roles: map[string]string{"payments-dev": "member", ClusterColumn: "Admin"}},
+			{UID: "u2", Username: `<b>"j|doe"</b>`, Mail: "jdoe@example.com", Roles: map[string]string{"payments-dev": "admin"}},
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, testMatrix(), "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want := `uid,username,mail,payments-dev,(cluster)
+u1,asmith,asmith@example.com,member,Admin
+u2,"<b>""j|doe""</b>",jdoe@example.com,admin,
+`
+	if b.String() != want {
+		t.Errorf("csv output\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, testMatrix(), "json"); err != nil {
+		t.Fatal(err)
+	}
+	var matrix Matrix
+	if err := json.Unmarshal(b.Bytes(), &matrix); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matrix, testMatrix()) {
+		t.Errorf("json output %+v, want %+v", matrix, testMatrix())
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	for _, format := range []string{"markdown", "md"} {
+		var b bytes.Buffer
+		if err := Write(&b, testMatrix(), format); err != nil {
+			t.Fatal(err)
+		}
+		want := `| uid | username | mail | payments-dev | (cluster) |
+| --- | --- | --- | --- | --- |
+| u1 | asmith | asmith@example.com | member | Admin |
+| u2 | <b>"j\|doe"</b> | jdoe@example.com | admin |  |
+`
+		if b.String() != want {
+			t.Errorf("%s output\n%s\nwant\n%s", format, b.String(), want)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, testMatrix(), "html"); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, want := range []string{
+		"2 users, 2 scopes",
+		"<th>uid</th><th>username</th><th>mail</th><th>payments-dev</th><th>(cluster)</th>",
+		`<td>u1</td><td>asmith</td><td>asmith@example.com</td><td class="role">member</td><td class="role">Admin</td>`,
+		`<td>&lt;b&gt;&#34;j|doe&#34;&lt;/b&gt;</td>`,
+		`<td class="role">admin</td><td></td>`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("html output does not contain %s\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "<b>") {
+		t.Errorf("html output contains unescaped markup\n%s", output)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, testMatrix(), "xml"); err == nil {
+		t.Error("Write() succeeded with an unknown format")
+	}
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"sort"
+	"strings"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+)
+
+// ClusterColumn is the matrix column holding the cluster role of the users
+const ClusterColumn = "(cluster)"
+
+// Filter restricts a matrix to a namespace, a role or a user, empty fields match everything
+type Filter struct {
+	Namespace string
+	Role      string
+	// User matches the uid, username or mail of a member
+	User string
+}
+
+// Row holds the access of a user, Roles maps a namespace, or ClusterColumn, to its role
+type Row struct {
+	UID      string            `json:"uid"`
+	Username string            `json:"username"`
+	Mail     string            `json:"mail"`
+	Dn       string            `json:"dn"`
+	Roles    map[string]string `json:"roles"`
+}
+
+// Matrix is the user x namespace access matrix
+type Matrix struct {
+	// Columns are the namespaces by name followed by ClusterColumn when any user has a cluster role
+	Columns []string `json:"columns"`
+	Rows    []Row    `json:"rows"`
+}
+
+// Build computes the access matrix of the given members
+func Build(clusterMembers []*v2.ClusterMember, projectMembers []*v2.ProjectMember, filter Filter) Matrix {
+	rows := map[string]*Row{}
+	columns := map[string]bool{}
+
+	add := func(name string, uid, username, mail, dn, column, role string) {
+		if !filter.matches(uid, username, mail, column, role) {
+			return
+		}
+		row, found := rows[name]
+		if !found {
+			row = &Row{UID: uid, Username: username, Mail: mail, Dn: dn, Roles: map[string]string{}}
+			rows[name] = row
+		}
+		row.Roles[column] = role
+		columns[column] = true
+	}
+
+	for _, member := range projectMembers {
+		add(member.Name, member.Spec.UID, member.Spec.Username, member.Spec.Mail, member.Spec.Dn, member.Namespace, member.Spec.Role)
+	}
+	for _, member := range clusterMembers {
+		add(member.Name, member.Spec.UID, member.Spec.Username, member.Spec.Mail, member.Spec.Dn, ClusterColumn, member.Spec.Role)
+	}
+
+	matrix := Matrix{Rows: make([]Row, 0, len(rows))}
+	for column := range columns {
+		if column != ClusterColumn {
+			matrix.Columns = append(matrix.Columns, column)
+		}
+	}
+	sort.Strings(matrix.Columns)
+	if columns[ClusterColumn] {
+		matrix.Columns = append(matrix.Columns, ClusterColumn)
+	}
+
+	for _, row := range rows {
+		matrix.Rows = append(matrix.Rows, *row)
+	}
+	sort.Slice(matrix.Rows, func(i, j int) bool {
+		if matrix.Rows[i].Username != matrix.Rows[j].Username {
+			return matrix.Rows[i].Username < matrix.Rows[j].Username
+		}
+		return matrix.Rows[i].UID < matrix.Rows[j].UID
+	})
+	return matrix
+}
+
+func (f Filter) matches(uid, username, mail, column, role string) bool {
+	if f.Namespace != "" && f.Namespace != column {
+		return false
+	}
+	if f.Role != "" && f.Role != role {
+		return false
+	}
+	if f.User != "" && f.User != uid && !strings.EqualFold(f.User, username) && !strings.EqualFold(f.User, mail) {
+		return false
+	}
+	return true
+}
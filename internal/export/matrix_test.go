@@ -0,0 +1,110 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func projectMember(namespace, name, username, mail, role string) *v2.ProjectMember {
+	return &v2.ProjectMember{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v2.ProjectMemberSpec{UID: name, Username: username, Mail: mail, Role: role},
+	}
+}
+
+func clusterMember(name, username, mail, role string) *v2.ClusterMember {
+	return &v2.ClusterMember{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v2.ClusterMemberSpec{UID: name, Username: username, Mail: mail, Role: role},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	projectMembers := []*v2.ProjectMember{
+		projectMember("payments-dev", "u2", "jdoe", "jdoe@example.com", "member"),
+		projectMember("billing-prod", "u2", "jdoe", "jdoe@example.com", "admin"),
+		projectMember("payments-dev", "u1", "asmith", "asmith@example.com", "member"),
+	}
+	clusterMembers := []*v2.ClusterMember{
+		clusterMember("u1", "asmith", "asmith@example.com", "Admin"),
+	}
+
+	tests := []struct {
+		name        string
+		filter      Filter
+		wantColumns []string
+		wantRows    map[string]map[string]string
+	}{
+		{
+			name:        "everything",
+			wantColumns: []string{"billing-prod", "payments-dev", ClusterColumn},
+			wantRows: map[string]map[string]string{
+				"asmith": {"payments-dev": "member", ClusterColumn: "Admin"},
+				"jdoe":   {"payments-dev": "member", "billing-prod": "admin"},
+			},
+		},
+		{
+			name:        "namespace",
+			filter:      Filter{Namespace: "billing-prod"},
+			wantColumns: []string{"billing-prod"},
+			wantRows:    map[string]map[string]string{"jdoe": {"billing-prod": "admin"}},
+		},
+		{
+			name:        "cluster column",
+			filter:      Filter{Namespace: ClusterColumn},
+			wantColumns: []string{ClusterColumn},
+			wantRows:    map[string]map[string]string{"asmith": {ClusterColumn: "Admin"}},
+		},
+		{
+			name:        "role",
+			filter:      Filter{Role: "member"},
+			wantColumns: []string{"payments-dev"},
+			wantRows: map[string]map[string]string{
+				"asmith": {"payments-dev": "member"},
+				"jdoe":   {"payments-dev": "member"},
+			},
+		},
+		{
+			name:        "user by mail",
+			filter:      Filter{User: "ASmith@Example.com"},
+			wantColumns: []string{"payments-dev", ClusterColumn},
+			wantRows:    map[string]map[string]string{"asmith": {"payments-dev": "member", ClusterColumn: "Admin"}},
+		},
+		{
+			name:        "user by uid",
+			filter:      Filter{User: "u2", Role: "admin"},
+			wantColumns: []string{"billing-prod"},
+			wantRows:    map[string]map[string]string{"jdoe": {"billing-prod": "admin"}},
+		},
+		{
+			name:     "no match",
+			filter:   Filter{User: "nobody"},
+			wantRows: map[string]map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matrix := Build(clusterMembers, projectMembers, test.filter)
+			if !reflect.DeepEqual(matrix.Columns, test.wantColumns) {
+				t.Errorf("columns %v, want %v", matrix.Columns, test.wantColumns)
+			}
+			rows := map[string]map[string]string{}
+			var usernames []string
+			for _, row := range matrix.Rows {
+				rows[row.Username] = row.Roles
+				usernames = append(usernames, row.Username)
+			}
+			if !reflect.DeepEqual(rows, test.wantRows) {
+				t.Errorf("rows %v, want %v", rows, test.wantRows)
+			}
+			for i := 1; i < len(usernames); i++ {
+				if usernames[i-1] > usernames[i] {
+					t.Errorf("rows are not sorted by username : %v", usernames)
+				}
+			}
+		})
+	}
+}
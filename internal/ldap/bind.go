@@ -0,0 +1,52 @@
+package ldap
+
+import (
+	"fmt"
+
+	"github.com/ca-gip/kubi-members/internal/utils"
+	ldap "github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldap/v3/gssapi"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+)
+
+// bind authenticates conn to host with the bind method of config
+func bind(conn *ldap.Conn, config utils.LdapConfig, host string) error {
+	switch config.BindMethod {
+	case utils.BindExternal:
+		// The identity is the TLS client certificate of the connection
+		return conn.ExternalBind()
+	case utils.BindGSSAPI:
+		return gssapiBind(conn, config.Kerberos, host)
+	default:
+		return conn.Bind(config.BindDN, config.BindPassword)
+	}
+}
+
+// gssapiBind binds with a Kerberos ticket obtained from the keytab. A client is
+// created for each connection as its security context is not shared, and the
+// keytab is read again so that a rotated keytab is used by new connections.
+func gssapiBind(conn *ldap.Conn, config utils.KerberosConfig, host string) error {
+	realm := config.Realm
+	if realm == "" {
+		krb5conf, err := krb5config.Load(config.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("unable to load kerberos configuration %s : %w", config.ConfigFile, err)
+		}
+		realm = krb5conf.LibDefaults.DefaultRealm
+		if realm == "" {
+			return fmt.Errorf("no kerberos realm set and no default_realm in %s", config.ConfigFile)
+		}
+	}
+
+	client, err := gssapi.NewClientWithKeytab(config.Username, realm, config.KeytabFile, config.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("unable to create kerberos client : %w", err)
+	}
+	defer client.Close()
+
+	spn := config.ServicePrincipal
+	if spn == "" {
+		spn = "ldap/" + host
+	}
+	return conn.GSSAPIBind(client, spn, "")
+}
@@ -1,13 +1,33 @@
 package ldap
 
+import "sync"
+
+// Cache holds the users already fetched during a sync, keyed by normalized DN.
+// It is safe for concurrent use.
 type Cache struct {
-	m map[string]*User
+	mu sync.RWMutex
+	m  map[string]*User
+}
+
+func NewCache() *Cache {
+	return &Cache{m: make(map[string]*User)}
 }
 
 func (c *Cache) Add(key string, user *User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.m[key] = user
 }
 
 func (c *Cache) Get(key string) *User {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.m[key]
 }
+
+// Reset forgets every cached user
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m = make(map[string]*User)
+}
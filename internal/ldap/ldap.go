@@ -2,18 +2,43 @@ package ldap
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ca-gip/kubi-members/internal/metrics"
 	"github.com/ca-gip/kubi-members/internal/utils"
 	ldap "github.com/go-ldap/ldap/v3"
 	"k8s.io/klog/v2"
-	"syscall"
 )
 
+const (
+	// matchingRuleInChain is the Active Directory LDAP_MATCHING_RULE_IN_CHAIN OID,
+	// walking the ancestry of an attribute server side
+	matchingRuleInChain = "1.2.840.113556.1.4.1941"
+	// activeDirectoryCapability is advertised in the rootDSE supportedCapabilities of AD servers
+	activeDirectoryCapability = "1.2.840.113556.1.4.800"
+
+	groupFilter = "(|(objectClass=groupOfNames)(objectClass=group))"
+)
+
+// ErrGroupNotFound is returned by Search when the group does not exist
+var ErrGroupNotFound = errors.New("group not found")
+
+// User is a person entry, its fields read from the attributes mapped by
+// utils.UserAttributes
 type User struct {
-	ID 		 string `ldap:"id"`
-	Dn       string `ldap:"dn"`
-	Username string `ldap:"displayName"`
-	Mail     string `ldap:"mail"`
+	ID          string
+	Dn          string
+	Username    string
+	DisplayName string
+	Mail        string
+	// Attributes holds the extra attributes of the mapping that are set
+	Attributes map[string]string
 }
 
 type Users []User
@@ -28,136 +53,647 @@ func (u Users) Exist(dn string) bool {
 }
 
 type Ldap struct {
-	Conn              *ldap.Conn
-	UserBase          string
-	UserFilter        string
-	UserKey           string
-	GroupBase         string
-	AppGroupBase      string
-	AdminGroupBase    string
-	CustomerGroupBase string
-	OpsGroupBase      string
+	UserBase string
+	// UserFilter selects the user entries, see utils.LdapConfig.UserSearchFilter
+	UserFilter   string
+	Attributes   utils.UserAttributes
+	ClusterRoles utils.Roles
+	// ProjectDefaultRole is the role granted by the source DN of a project
+	ProjectDefaultRole string
+
+	GroupMaxDepth          int
+	UseMatchingRuleInChain bool
+	PageSize               uint32
+	BatchSize              int
+
+	pool *Pool
+	// cache avoids fetching again users member of several groups during a sync
+	cache *Cache
 }
 
-func NewLdap() *Ldap {
+func NewLdap(config utils.Config) *Ldap {
+	l := &Ldap{cache: NewCache()}
+	if err := l.Reload(config); err != nil {
+		klog.Fatalf("%s", err)
+		syscall.Exit(1)
+	}
+	return l
+}
 
-	config := utils.LoadConfig()
+// Reload applies config with a new connection pool. The current settings and
+// pool are kept when no server can be bound with the new configuration. It
+// must not be called while a search is running.
+func (l *Ldap) Reload(cfg utils.Config) error {
+	config := cfg.LDAP
 	klog.InfoS("Creating LDAP Client with specified config",
 		"UserBase", config.UserBase,
-		"UserFilter", config.UserFilter,
-		"OpsGroupBase", config.OpsGroupBase,
-		"AppGroupBase", config.AppGroupBase,
-		"AdminGroupBase",config.AdminGroupBase,
-		"CustomerGroupBase", config.CustomerGroupBase,
-		"UserKey", config.UserKey)
-	tlsConfig := &tls.Config{
-		ServerName:         config.Host,
-		InsecureSkipVerify: config.SkipTLSVerification,
-	}
-
-	var (
-		err  error
-		conn *ldap.Conn
-	)
-
-	if config.UseSSL {
-		conn, err = ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", config.Host, config.Port), tlsConfig)
-	} else {
-		conn, err = ldap.Dial("tcp", fmt.Sprintf("%s:%d", config.Host, config.Port))
-	}
+		"UserFilter", config.UserSearchFilter(),
+		"Attributes", config.Attributes,
+		"ClusterRoles", cfg.ClusterRoles,
+		"ProjectDefaultRole", cfg.ProjectDefaultRole,
+		"GroupMaxDepth", config.GroupMaxDepth,
+		"UseMatchingRuleInChain", config.UseMatchingRuleInChain,
+		"PageSize", config.PageSize,
+		"BatchSize", config.BatchSize,
+		"Servers", config.Hosts,
+		"PoolSize", config.PoolSize)
 
-	if config.StartTLS {
-		err = conn.StartTLS(tlsConfig)
-		if err != nil {
-			klog.Fatal("unable to setup TLS connection")
-			syscall.Exit(1)
+	servers := make([]string, 0, len(config.Hosts))
+	for _, host := range config.Hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, strconv.Itoa(config.Port))
 		}
+		servers = append(servers, host)
 	}
 
+	tlsConfig, err := config.TLSConfig()
 	if err != nil {
-		klog.Fatalf("unable to create ldap connector for %s:%d", config.Host, config.Port)
-		syscall.Exit(1)
+		return err
+	}
+	switch {
+	case !config.UseSSL && !config.StartTLS && config.BindMethod == utils.BindSimple:
+		klog.Warning("LDAP connections are not encrypted, the bind password is sent in clear text, set LDAP_USE_SSL or LDAP_START_TLS")
+	case config.SkipTLSVerification:
+		klog.Warning("!!! TLS certificate verification of the LDAP servers is DISABLED by LDAP_SKIP_TLS_VERIFICATION, " +
+			"connections and the bind password are exposed to man-in-the-middle attacks. Configure LDAP_CA_FILE instead !!!")
 	}
 
-	// Bind with BindAccount
-	err = conn.Bind(config.BindDN, config.BindPassword)
+	pool := NewPool(servers, config.PoolSize, config.Retries, config.RetryBackoff.Duration, dialer(config, tlsConfig))
 
+	// Fail fast when no server can be reached or bound
+	err = pool.Do(func(conn *ldap.Conn) error { return nil })
 	if err != nil {
-		klog.Fatalf("Error while binding : %s", err)
-		syscall.Exit(1)
+		pool.Close()
+		return fmt.Errorf("unable to create ldap connector for %v : %w", servers, err)
+	}
+
+	previous := l.pool
+	l.pool = pool
+	l.UserBase = config.UserBase
+	l.UserFilter = config.UserSearchFilter()
+	l.Attributes = config.Attributes
+	l.ClusterRoles = cfg.ClusterRoles
+	l.ProjectDefaultRole = cfg.ProjectDefaultRole
+	l.GroupMaxDepth = config.GroupMaxDepth
+	l.UseMatchingRuleInChain = config.UseMatchingRuleInChain
+	l.PageSize = uint32(config.PageSize)
+	l.BatchSize = config.BatchSize
+	l.ResetCache()
+
+	if l.UseMatchingRuleInChain && !l.supportsMatchingRuleInChain() {
+		klog.Warning("LDAP_MATCHING_RULE_IN_CHAIN is not supported by the server, falling back to recursive group expansion")
+		l.UseMatchingRuleInChain = false
+	}
+
+	if previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// dialer returns the function opening a connection to a server and binding
+// with the service account, using the bind method of config
+func dialer(config utils.LdapConfig, baseTLSConfig *tls.Config) DialFunc {
+	return func(addr string) (*ldap.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConfig := baseTLSConfig.Clone()
+		tlsConfig.ServerName = host
+
+		scheme := "ldap"
+		if config.UseSSL {
+			scheme = "ldaps"
+		}
+
+		conn, err := ldap.DialURL(fmt.Sprintf("%s://%s", scheme, addr),
+			ldap.DialWithDialer(&net.Dialer{Timeout: config.DialTimeout.Duration}),
+			ldap.DialWithTLSConfig(tlsConfig))
+		if err != nil {
+			return nil, err
+		}
+		conn.SetTimeout(config.ReadTimeout.Duration)
+
+		if config.StartTLS {
+			if err = conn.StartTLS(tlsConfig); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("unable to setup TLS connection : %w", err)
+			}
+		}
+
+		if err = bind(conn, config, host); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error while binding : %w", err)
+		}
+
+		return conn, nil
+	}
+}
+
+// supportsMatchingRuleInChain reports whether the server is an Active Directory
+// and can resolve nested groups itself. A UserBase is required to scope the search.
+func (l *Ldap) supportsMatchingRuleInChain() bool {
+	if l.UserBase == "" {
+		klog.Warning("LDAP_MATCHING_RULE_IN_CHAIN requires LDAP_USERBASE to be set")
+		return false
+	}
+
+	res, err := l.search(&ldap.SearchRequest{
+		BaseDN:     "",
+		Scope:      ldap.ScopeBaseObject,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"supportedCapabilities"},
+	})
+	if err != nil || res == nil || len(res.Entries) != 1 {
+		klog.Warningf("Could not read LDAP rootDSE : %v", err)
+		return false
 	}
 
-	return &Ldap{
-		Conn:              conn,
-		UserBase:          config.UserBase,
-		UserKey:           config.UserKey,
-		GroupBase:         config.GroupBase,
-		AppGroupBase:      config.AppGroupBase,
-		AdminGroupBase:    config.AdminGroupBase,
-		CustomerGroupBase: config.CustomerGroupBase,
-		OpsGroupBase:      config.OpsGroupBase,
+	for _, capability := range res.Entries[0].GetAttributeValues("supportedCapabilities") {
+		if capability == activeDirectoryCapability {
+			return true
+		}
 	}
+	return false
+}
+
+// search runs request on a pooled connection. Subtree searches use the Simple
+// Paged Results control so that large result sets are not truncated by the
+// server size limit.
+func (l *Ldap) search(request *ldap.SearchRequest) (res *ldap.SearchResult, err error) {
+	err = l.pool.Do(func(conn *ldap.Conn) error {
+		// Paging adds a control holding the cookie, each attempt starts from a fresh copy
+		attempt := *request
+		attempt.Controls = append([]ldap.Control(nil), request.Controls...)
 
+		if l.PageSize == 0 || request.Scope == ldap.ScopeBaseObject {
+			res, err = conn.Search(&attempt)
+		} else {
+			res, err = conn.SearchWithPaging(&attempt, l.PageSize)
+		}
+		return err
+	})
+	return
 }
 
-func (l *Ldap) searchGroupMember(groupDN string) (members []string, err error) {
-	res, err := l.Conn.Search(&ldap.SearchRequest{
+// searchGroupMember returns the direct members of groupDN, found is false when
+// groupDN is not a group. Active Directory returns at most MaxValRange values
+// of member per search, the remaining ranges are requested until the last one.
+func (l *Ldap) searchGroupMember(groupDN string) (members []string, found bool, err error) {
+	defer metrics.ObserveLDAP("searchGroupMember", time.Now(), &err)
+
+	res, err := l.search(&ldap.SearchRequest{
 		BaseDN:       groupDN,
 		Scope:        ldap.ScopeWholeSubtree,
 		DerefAliases: ldap.NeverDerefAliases,
 		SizeLimit:    0,
 		TimeLimit:    30,
 		TypesOnly:    false,
-		Filter:       "(|(objectClass=groupOfNames)(objectClass=group))",
+		Filter:       groupFilter,
 		Attributes:   []string{"member"},
 	})
 
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		err = nil
+		return
+	}
 	if err != nil || res == nil || len(res.Entries) != 1 {
 		return
 	}
 
-	members = res.Entries[0].GetAttributeValues("member")
+	group := res.Entries[0]
+	members, next := rangedValues(group, "member")
+	found = true
+
+	for next >= 0 {
+		res, err = l.search(&ldap.SearchRequest{
+			BaseDN:       group.DN,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			TimeLimit:    30,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{fmt.Sprintf("member;range=%d-*", next)},
+		})
+		if err != nil {
+			return
+		}
+		if res == nil || len(res.Entries) != 1 {
+			err = fmt.Errorf("group %s disappeared while reading member range %d", group.DN, next)
+			return
+		}
+
+		var values []string
+		values, next = rangedValues(res.Entries[0], "member")
+		members = append(members, values...)
+	}
+
+	return
+}
+
+// rangedValues returns the values of attribute from entry. When the server
+// answered with a ranged attribute (attribute;range=low-high), next is the
+// lower bound of the following range, and -1 once the last range was read.
+func rangedValues(entry *ldap.Entry, attribute string) (values []string, next int) {
+	next = -1
+	prefix := strings.ToLower(attribute) + ";range="
+
+	for _, attr := range entry.Attributes {
+		name := strings.ToLower(attr.Name)
+		if name == strings.ToLower(attribute) {
+			values = append(values, attr.Values...)
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		values = append(values, attr.Values...)
+		bounds := strings.SplitN(strings.TrimPrefix(name, prefix), "-", 2)
+		if len(bounds) == 2 && bounds[1] != "*" {
+			high, err := strconv.Atoi(bounds[1])
+			if err == nil {
+				next = high + 1
+			}
+		}
+	}
 
 	return
 }
 
 func (l *Ldap) searchUser(userDN string) (user *User, err error) {
-	res, err := l.Conn.Search(&ldap.SearchRequest{
+	defer metrics.ObserveLDAP("searchUser", time.Now(), &err)
+
+	res, err := l.search(&ldap.SearchRequest{
 		BaseDN:       userDN,
 		Scope:        ldap.ScopeWholeSubtree,
 		DerefAliases: ldap.NeverDerefAliases,
 		SizeLimit:    1,
 		TimeLimit:    10,
 		TypesOnly:    false,
-		Filter:       "(|(objectClass=person)(objectClass=organizationalPerson))",
-		Attributes:   []string{"cn","mail",l.UserKey},
+		Filter:       l.UserFilter,
+		Attributes:   l.userAttributes(),
 	})
 
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		// Dangling member, the entry was removed from the directory
+		err = nil
+		return
+	}
 	if err != nil || res == nil || len(res.Entries) == 0 {
 		return
 	} else {
-		user = &User{
-			Dn:       userDN,
-			Username: res.Entries[0].GetAttributeValue("cn"),
-			Mail:     res.Entries[0].GetAttributeValue("mail"),
-			ID:		  res.Entries[0].GetAttributeValue(l.UserKey),
+		user = l.entryToUser(res.Entries[0])
+		if user != nil {
+			user.Dn = userDN
 		}
 		return
 	}
 }
 
+// searchUsers resolves the users among dns, keyed by normalized DN. Cached users
+// are reused, the others are fetched by batches of BatchSize with a single
+// search under UserBase, or one by one when batches are disabled. When UserBase
+// is set, the DNs out of it are not users. DNs that are not users, such as
+// nested groups, are absent from the result.
+func (l *Ldap) searchUsers(dns []string) (users map[string]*User, err error) {
+	users = make(map[string]*User, len(dns))
+
+	var pending []string
+	for _, dn := range dns {
+		key := normalizeDN(dn)
+		if user := l.cache.Get(key); user != nil {
+			users[key] = user
+			continue
+		}
+		if l.UserBase != "" && !isUnder(key, normalizeDN(l.UserBase)) {
+			continue
+		}
+		pending = append(pending, dn)
+	}
+
+	searched := map[string]bool{}
+	if l.UserBase != "" && l.BatchSize > 0 {
+		for start := 0; start < len(pending); start += l.BatchSize {
+			end := start + l.BatchSize
+			if end > len(pending) {
+				end = len(pending)
+			}
+			if err = l.searchUsersBatch(pending[start:end], users, searched); err != nil {
+				return
+			}
+		}
+	}
+
+	for _, dn := range pending {
+		key := normalizeDN(dn)
+		if searched[key] {
+			continue
+		}
+		var user *User
+		user, err = l.searchUser(dn)
+		if err != nil {
+			return
+		}
+		if user != nil {
+			l.cache.Add(key, user)
+			users[key] = user
+		}
+	}
+
+	return
+}
+
+// searchUsersBatch looks up dns under UserBase by their RDN and keeps the
+// entries whose DN was requested, the DNs looked up being added to searched
+func (l *Ldap) searchUsersBatch(dns []string, users map[string]*User, searched map[string]bool) (err error) {
+	requested := make(map[string]bool, len(dns))
+	var filter strings.Builder
+	for _, dn := range dns {
+		rdnFilter, ok := rdnFilter(dn)
+		if !ok {
+			continue
+		}
+		requested[normalizeDN(dn)] = true
+		filter.WriteString(rdnFilter)
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+
+	defer metrics.ObserveLDAP("searchUsers", time.Now(), &err)
+	res, err := l.search(&ldap.SearchRequest{
+		BaseDN:       l.UserBase,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    0,
+		TimeLimit:    30,
+		TypesOnly:    false,
+		Filter:       fmt.Sprintf("(&%s(|%s))", l.UserFilter, filter.String()),
+		Attributes:   l.userAttributes(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range res.Entries {
+		key := normalizeDN(entry.DN)
+		if !requested[key] {
+			continue
+		}
+		user := l.entryToUser(entry)
+		if user == nil {
+			continue
+		}
+		l.cache.Add(key, user)
+		users[key] = user
+	}
+	for key := range requested {
+		searched[key] = true
+	}
+	return nil
+}
+
+// userAttributes returns every attribute of the mapping, to be read by user searches
+func (l *Ldap) userAttributes() []string {
+	var attributes []string
+	seen := map[string]bool{}
+	for _, names := range [][]string{l.Attributes.ID, l.Attributes.Username, l.Attributes.DisplayName, l.Attributes.Mail, l.Attributes.Extra} {
+		for _, name := range names {
+			if !seen[strings.ToLower(name)] {
+				seen[strings.ToLower(name)] = true
+				attributes = append(attributes, name)
+			}
+		}
+	}
+	return attributes
+}
+
+// entryToUser maps the attributes of entry to a User, nil when entry has none
+// of the ID attributes as members are named after the ID
+func (l *Ldap) entryToUser(entry *ldap.Entry) *User {
+	id := firstValue(entry, l.Attributes.ID)
+	if id == "" {
+		klog.Warningf("Skipping user %s, none of the ID attributes %v is set", entry.DN, l.Attributes.ID)
+		return nil
+	}
+	user := &User{
+		Dn:          entry.DN,
+		ID:          id,
+		Username:    firstValue(entry, l.Attributes.Username),
+		DisplayName: firstValue(entry, l.Attributes.DisplayName),
+		Mail:        firstValue(entry, l.Attributes.Mail),
+	}
+	for _, name := range l.Attributes.Extra {
+		if value := entry.GetEqualFoldAttributeValue(name); value != "" {
+			if user.Attributes == nil {
+				user.Attributes = map[string]string{}
+			}
+			user.Attributes[name] = value
+		}
+	}
+	return user
+}
+
+// firstValue returns the value of the first of names set on entry, attribute
+// names being case insensitive
+func firstValue(entry *ldap.Entry, names []string) string {
+	for _, name := range names {
+		if value := entry.GetEqualFoldAttributeValue(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ResetCache forgets the users fetched so far, it is called at the start of each sync
+func (l *Ldap) ResetCache() {
+	l.cache.Reset()
+}
+
+// Search returns the users member of groupDN, including the members of its
+// nested groups. An error is returned when the membership could not be fully
+// resolved, callers must not consider users as complete in that case.
 func (l *Ldap) Search(groupDN string) (users Users, err error) {
-	membersDn, err := l.searchGroupMember(groupDN)
+	if l.UseMatchingRuleInChain {
+		var found bool
+		found, err = l.groupExists(groupDN)
+		if err != nil {
+			return
+		}
+		if !found {
+			err = fmt.Errorf("%s : %w", groupDN, ErrGroupNotFound)
+			return
+		}
+		return l.searchUsersInChain(groupDN)
+	}
+
+	visited := map[string]bool{normalizeDN(groupDN): true}
+	err = l.expandGroup(groupDN, 0, visited, &users)
+	return
+}
+
+// expandGroup appends the users of groupDN to users and recurses into nested
+// groups up to GroupMaxDepth. visited holds the normalized DNs already seen,
+// which breaks membership cycles and deduplicates users.
+func (l *Ldap) expandGroup(groupDN string, depth int, visited map[string]bool, users *Users) error {
+	membersDn, found, err := l.searchGroupMember(groupDN)
 	if err != nil {
-		return
+		return err
+	}
+	if !found && depth == 0 {
+		return fmt.Errorf("%s : %w", groupDN, ErrGroupNotFound)
+	}
+	if !found {
+		klog.V(4).Infof("Ignored member %s, neither a user nor a group", groupDN)
+		return nil
 	}
 
+	var pending []string
 	for _, memberDn := range membersDn {
-		user, _ := l.searchUser(memberDn)
-		if user != nil {
-			users = append(users, *user)
+		key := normalizeDN(memberDn)
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+		pending = append(pending, memberDn)
+	}
+
+	resolved, err := l.searchUsers(pending)
+	if err != nil {
+		return err
+	}
+
+	for _, memberDn := range pending {
+		if user, found := resolved[normalizeDN(memberDn)]; found {
+			*users = append(*users, *user)
+			continue
+		}
+
+		if depth >= l.GroupMaxDepth {
+			// Only nested groups are cut off, other members are not users
+			isGroup, err := l.groupExists(memberDn)
+			if err != nil {
+				return err
+			}
+			if isGroup {
+				klog.Warningf("Ignored nested group %s of %s, maximum nested group depth %d reached", memberDn, groupDN, l.GroupMaxDepth)
+			} else {
+				klog.V(4).Infof("Ignored member %s of %s, not a user", memberDn, groupDN)
+			}
+			continue
 		}
+
+		if err := l.expandGroup(memberDn, depth+1, visited, users); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Ldap) groupExists(groupDN string) (bool, error) {
+	res, err := l.search(&ldap.SearchRequest{
+		BaseDN:       groupDN,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		TimeLimit:    10,
+		Filter:       groupFilter,
+		Attributes:   []string{"1.1"},
+	})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(res.Entries) == 1, nil
+}
+
+// searchUsersInChain lets an Active Directory server resolve the transitive
+// members of groupDN in a single search under UserBase
+func (l *Ldap) searchUsersInChain(groupDN string) (users Users, err error) {
+	defer metrics.ObserveLDAP("searchUsersInChain", time.Now(), &err)
+
+	res, err := l.search(&ldap.SearchRequest{
+		BaseDN:       l.UserBase,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    0,
+		TimeLimit:    30,
+		TypesOnly:    false,
+		Filter:       fmt.Sprintf("(&%s(memberOf:%s:=%s))", l.UserFilter, matchingRuleInChain, ldap.EscapeFilter(groupDN)),
+		Attributes:   l.userAttributes(),
+	})
+	if err != nil || res == nil {
+		return
 	}
 
+	for _, entry := range res.Entries {
+		user := l.entryToUser(entry)
+		if user == nil {
+			continue
+		}
+		l.cache.Add(normalizeDN(entry.DN), user)
+		users = append(users, *user)
+	}
 	return
 }
+
+// rdnFilter returns a filter matching the relative DN of dn
+func rdnFilter(dn string) (string, bool) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return "", false
+	}
+
+	var filter strings.Builder
+	attributes := parsed.RDNs[0].Attributes
+	if len(attributes) > 1 {
+		filter.WriteString("(&")
+	}
+	for _, attribute := range attributes {
+		fmt.Fprintf(&filter, "(%s=%s)", attribute.Type, ldap.EscapeFilter(attribute.Value))
+	}
+	if len(attributes) > 1 {
+		filter.WriteString(")")
+	}
+	return filter.String(), true
+}
+
+// isUnder reports whether the normalized dn is base or one of its descendants
+func isUnder(dn string, base string) bool {
+	return dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+// normalizeDN returns a comparable form of dn, falling back to lower case when
+// it cannot be parsed
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attributes := make([]string, 0, len(rdn.Attributes))
+		for _, attribute := range rdn.Attributes {
+			attributes = append(attributes, strings.ToLower(attribute.Type)+"="+escapeDNValue(strings.ToLower(attribute.Value)))
+		}
+		rdns = append(rdns, strings.Join(attributes, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
+
+// escapeDNValue escapes the special characters of an attribute value, so that
+// normalized DNs with escaped separators do not collide with other DNs
+func escapeDNValue(value string) string {
+	var escaped strings.Builder
+	for i, r := range value {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r),
+			r == '#' && i == 0,
+			r == ' ' && (i == 0 || i == len(value)-1):
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
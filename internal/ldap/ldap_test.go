@@ -0,0 +1,145 @@
+package ldap
+
+import (
+	"reflect"
+	"testing"
+
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func TestRangedValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string][]string
+		wantValues []string
+		wantNext   int
+	}{
+		{
+			name:       "not ranged",
+			attributes: map[string][]string{"member": {"cn=a", "cn=b"}},
+			wantValues: []string{"cn=a", "cn=b"},
+			wantNext:   -1,
+		},
+		{
+			name:       "first range",
+			attributes: map[string][]string{"member;range=0-1499": {"cn=a", "cn=b"}},
+			wantValues: []string{"cn=a", "cn=b"},
+			wantNext:   1500,
+		},
+		{
+			name:       "following range",
+			attributes: map[string][]string{"member;range=1500-2999": {"cn=c"}},
+			wantValues: []string{"cn=c"},
+			wantNext:   3000,
+		},
+		{
+			name:       "last range",
+			attributes: map[string][]string{"member;range=3000-*": {"cn=d"}},
+			wantValues: []string{"cn=d"},
+			wantNext:   -1,
+		},
+		{
+			name:       "attribute case",
+			attributes: map[string][]string{"Member;Range=0-1": {"cn=a", "cn=b"}},
+			wantValues: []string{"cn=a", "cn=b"},
+			wantNext:   2,
+		},
+		{
+			name:       "malformed range",
+			attributes: map[string][]string{"member;range=0-x": {"cn=a"}},
+			wantValues: []string{"cn=a"},
+			wantNext:   -1,
+		},
+		{
+			name:       "other attribute",
+			attributes: map[string][]string{"memberOf": {"cn=g"}, "memberof;range=0-1": {"cn=h"}},
+			wantNext:   -1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := ldap.NewEntry("cn=group,dc=example,dc=com", test.attributes)
+			values, next := rangedValues(entry, "member")
+			if !reflect.DeepEqual(values, test.wantValues) {
+				t.Errorf("rangedValues() values = %v, want %v", values, test.wantValues)
+			}
+			if next != test.wantNext {
+				t.Errorf("rangedValues() next = %d, want %d", next, test.wantNext)
+			}
+		})
+	}
+}
+
+func TestRdnFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		dn         string
+		wantFilter string
+		wantOk     bool
+	}{
+		{"simple", "cn=jdoe,ou=users,dc=example,dc=com", "(cn=jdoe)", true},
+		{"attribute case", "CN=JDoe,OU=Users,DC=example,DC=com", "(CN=JDoe)", true},
+		{"spacing", "cn = jdoe , ou=users,dc=example,dc=com", "(cn=jdoe)", true},
+		{"escaped comma", `cn=Doe\, John,ou=users,dc=example,dc=com`, "(cn=Doe, John)", true},
+		{"filter characters", `cn=a*b(c)\\d,dc=com`, `(cn=a\2ab\28c\29\5cd)`, true},
+		{"multi-valued", "cn=jdoe+uid=42,dc=example,dc=com", "(&(cn=jdoe)(uid=42))", true},
+		{"empty", "", "", false},
+		{"invalid", "not a dn", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, ok := rdnFilter(test.dn)
+			if filter != test.wantFilter || ok != test.wantOk {
+				t.Errorf("rdnFilter(%q) = %q, %v, want %q, %v", test.dn, filter, ok, test.wantFilter, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestNormalizeDN(t *testing.T) {
+	tests := []struct {
+		name string
+		dn   string
+		want string
+	}{
+		{"normalized", "cn=jdoe,ou=users,dc=example,dc=com", "cn=jdoe,ou=users,dc=example,dc=com"},
+		{"case", "CN=JDoe,OU=Users,DC=Example,DC=com", "cn=jdoe,ou=users,dc=example,dc=com"},
+		{"spacing", "cn=jdoe, ou=users , dc = example,dc=com", "cn=jdoe,ou=users,dc=example,dc=com"},
+		{"escaped comma", `CN=Doe\, John,OU=Users,DC=example,DC=com`, `cn=doe\, john,ou=users,dc=example,dc=com`},
+		{"escaped separators", `cn=a\+b\=c\;d,dc=com`, `cn=a\+b\=c\;d,dc=com`},
+		{"multi-valued", "CN=JDoe+UID=42,DC=com", "cn=jdoe+uid=42,dc=com"},
+		{"invalid", "Not A DN", "not a dn"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := normalizeDN(test.dn); got != test.want {
+				t.Errorf("normalizeDN(%q) = %q, want %q", test.dn, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsUnder(t *testing.T) {
+	base := normalizeDN("OU=Users,DC=example,DC=com")
+	tests := []struct {
+		name string
+		dn   string
+		want bool
+	}{
+		{"base", "ou=users, dc=example, dc=com", true},
+		{"child", "CN=JDoe,OU=Users,DC=example,DC=com", true},
+		{"grandchild", "cn=jdoe,ou=team,ou=users,dc=example,dc=com", true},
+		{"escaped comma", `cn=Doe\, John,ou=users,dc=example,dc=com`, true},
+		{"sibling", "cn=jdoe,ou=groups,dc=example,dc=com", false},
+		{"parent", "dc=example,dc=com", false},
+		{"suffix of an rdn", "cn=jdoe,ou=otherusers,dc=example,dc=com", false},
+		{"escaped comma in the rdn", `cn=jdoe\,ou=users,dc=example,dc=com`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isUnder(normalizeDN(test.dn), base); got != test.want {
+				t.Errorf("isUnder(%q, %q) = %v, want %v", test.dn, base, got, test.want)
+			}
+		})
+	}
+}
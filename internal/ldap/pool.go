@@ -0,0 +1,166 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	ldap "github.com/go-ldap/ldap/v3"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// DialFunc opens and binds a connection to addr
+type DialFunc func(addr string) (*ldap.Conn, error)
+
+// Pool hands out bound connections to a list of failover LDAP servers. Broken
+// connections are discarded and operations failing on a network error or an
+// unavailable server are retried on a new connection with an exponential backoff.
+type Pool struct {
+	servers []string
+	dial    DialFunc
+	retries int
+	backoff time.Duration
+
+	// slots bounds the number of open connections, idle holds the released ones
+	slots chan struct{}
+	idle  chan *ldap.Conn
+
+	mu      sync.Mutex
+	current int
+	// closed is set by Close, connections released afterwards are closed
+	closed bool
+}
+
+func NewPool(servers []string, size int, retries int, backoff time.Duration, dial DialFunc) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		servers: servers,
+		dial:    dial,
+		retries: retries,
+		backoff: backoff,
+		slots:   make(chan struct{}, size),
+		idle:    make(chan *ldap.Conn, size),
+	}
+}
+
+// Do runs fn with a pooled connection, retrying on another connection when fn
+// fails with a retryable error
+func (p *Pool) Do(fn func(conn *ldap.Conn) error) (err error) {
+	backoff := wait.Backoff{Duration: p.backoff, Factor: 2, Jitter: 0.1, Steps: p.retries}
+
+	for attempt := 0; ; attempt++ {
+		var conn *ldap.Conn
+		conn, err = p.get()
+		if err == nil {
+			err = fn(conn)
+			if err == nil || !isRetryable(err) {
+				p.put(conn)
+				return
+			}
+			p.discard(conn)
+		}
+
+		if attempt >= p.retries {
+			return
+		}
+		delay := backoff.Step()
+		klog.Warningf("LDAP operation failed, retrying in %s (%d/%d) : %s", delay, attempt+1, p.retries, err)
+		time.Sleep(delay)
+	}
+}
+
+// Close closes the idle connections, connections in use are closed when released
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	for {
+		select {
+		case conn := <-p.idle:
+			p.discard(conn)
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) get() (*ldap.Conn, error) {
+	select {
+	case conn := <-p.idle:
+		if !conn.IsClosing() {
+			return conn, nil
+		}
+		p.discard(conn)
+	default:
+	}
+
+	p.slots <- struct{}{}
+	conn, err := p.connect()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (p *Pool) put(conn *ldap.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || conn.IsClosing() {
+		p.discard(conn)
+		return
+	}
+	// Never blocks, idle holds as many connections as slots allows
+	p.idle <- conn
+}
+
+func (p *Pool) discard(conn *ldap.Conn) {
+	conn.Close()
+	<-p.slots
+}
+
+// connect tries every server once, starting with the last one reachable
+func (p *Pool) connect() (*ldap.Conn, error) {
+	if len(p.servers) == 0 {
+		return nil, errors.New("no LDAP server configured")
+	}
+
+	p.mu.Lock()
+	start := p.current
+	p.mu.Unlock()
+
+	var errs []error
+	for i := 0; i < len(p.servers); i++ {
+		index := (start + i) % len(p.servers)
+		conn, err := p.dial(p.servers[index])
+		if err != nil {
+			klog.Warningf("Could not connect to LDAP server %s : %s", p.servers[index], err)
+			errs = append(errs, err)
+			continue
+		}
+
+		p.mu.Lock()
+		if p.current != index {
+			klog.Infof("Failing over to LDAP server %s", p.servers[index])
+			p.current = index
+		}
+		p.mu.Unlock()
+		return conn, nil
+	}
+
+	return nil, fmt.Errorf("no LDAP server reachable : %v", errs)
+}
+
+func isRetryable(err error) bool {
+	if ldap.IsErrorAnyOf(err, ldap.ErrorNetwork, ldap.LDAPResultUnavailable, ldap.LDAPResultBusy, ldap.LDAPResultServerDown, ldap.LDAPResultTimeout) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const namespace = "kubi_members"
+
+// Registry holds every kubi-members metric
+var Registry = prometheus.NewRegistry()
+
+var (
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of the syncs of members, by scope (all, cluster or project).",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"scope"})
+
+	lastSuccessfulSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last sync without error, by scope (all, cluster or project).",
+	}, []string{"scope"})
+
+	projectSyncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "project_sync_errors_total",
+		Help:      "Number of syncs of a project whose members could not be computed.",
+	}, []string{"project"})
+
+	projectLastSuccessfulSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "project_last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last sync of a project without error.",
+	}, []string{"project"})
+
+	ldapDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ldap_request_duration_seconds",
+		Help:      "Duration of the LDAP operations, by operation and result (success or error).",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"operation", "result"})
+
+	ldapErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ldap_request_errors_total",
+		Help:      "Number of failed LDAP operations, by operation.",
+	}, []string{"operation"})
+
+	projectMembers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "project_members",
+		Help:      "Number of members of a project, by role.",
+	}, []string{"project", "role"})
+
+	clusterMembers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cluster_members",
+		Help:      "Number of cluster members, by role.",
+	}, []string{"role"})
+
+	memberChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "member_changes_total",
+		Help:      "Number of ProjectMember and ClusterMember resources written, by kind and operation (create, update or delete).",
+	}, []string{"kind", "operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		syncDuration, lastSuccessfulSync, projectSyncErrors, projectLastSuccessfulSync, ldapDuration, ldapErrors, projectMembers, clusterMembers, memberChanges,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Push sends the metrics to the Pushgateway at url under job
+func Push(url string, job string) error {
+	return push.New(url, job).Gatherer(Registry).Push()
+}
+
+// ObserveSync records a sync of scope started at start, err being its result
+func ObserveSync(scope string, start time.Time, err error) {
+	syncDuration.WithLabelValues(scope).Observe(time.Since(start).Seconds())
+	if err == nil {
+		lastSuccessfulSync.WithLabelValues(scope).SetToCurrentTime()
+	}
+}
+
+// ObserveProjectSync records the result of a sync of project
+func ObserveProjectSync(project string, err error) {
+	if err != nil {
+		projectSyncErrors.WithLabelValues(project).Inc()
+		return
+	}
+	projectSyncErrors.WithLabelValues(project).Add(0)
+	projectLastSuccessfulSync.WithLabelValues(project).SetToCurrentTime()
+}
+
+// ObserveLDAP records an LDAP operation started at start, meant to be deferred
+// with a pointer to the named error result of the operation
+func ObserveLDAP(operation string, start time.Time, err *error) {
+	result := "success"
+	if *err != nil {
+		result = "error"
+		ldapErrors.WithLabelValues(operation).Inc()
+	}
+	ldapDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+// MemberChanged counts a write of a member of kind
+func MemberChanged(kind string, operation string) {
+	memberChanges.WithLabelValues(kind, operation).Inc()
+}
+
+var (
+	mu           sync.Mutex
+	projectRoles = map[string]map[string]bool{}
+)
+
+// SetProjectMembers records the number of members of project by role
+func SetProjectMembers(project string, countByRole map[string]int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for role := range projectRoles[project] {
+		if _, ok := countByRole[role]; !ok {
+			projectMembers.DeleteLabelValues(project, role)
+		}
+	}
+	projectRoles[project] = map[string]bool{}
+	for role, count := range countByRole {
+		projectMembers.WithLabelValues(project, role).Set(float64(count))
+		projectRoles[project][role] = true
+	}
+}
+
+// DeleteProjectMembers forgets the members and syncs of a deleted project
+func DeleteProjectMembers(project string) {
+	SetProjectMembers(project, nil)
+	projectSyncErrors.DeleteLabelValues(project)
+	projectLastSuccessfulSync.DeleteLabelValues(project)
+	mu.Lock()
+	delete(projectRoles, project)
+	mu.Unlock()
+}
+
+// SetClusterMembers records the number of cluster members by role
+func SetClusterMembers(countByRole map[string]int) {
+	clusterMembers.Reset()
+	for role, count := range countByRole {
+		clusterMembers.WithLabelValues(role).Set(float64(count))
+	}
+}
@@ -1,36 +1,176 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	goldap "github.com/go-ldap/ldap/v3"
 	"github.com/joho/godotenv"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 )
 
+// ConfigAPIVersion is the version of the configuration file format
+const ConfigAPIVersion = "kubi-members/v1"
+
+const redacted = "<redacted>"
+
+// attributeName matches the LDAP attribute descriptions, names or OIDs
+var attributeName = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*|[0-9]+(\.[0-9]+)*)$`)
+
+// Config is the configuration of kubi-members, read from a YAML or JSON file
+// then overridden by the historical environment variables
+type Config struct {
+	APIVersion string     `json:"apiVersion"`
+	LDAP       LdapConfig `json:"ldap"`
+
+	// ClusterRoles are read from clusterRoles or CLUSTER_ROLES, a list of
+	// {name, groupDNs, priority}, else from the legacy LDAP_*_GROUPBASE variables
+	ClusterRoles Roles `json:"clusterRoles"`
+	// ProjectDefaultRole is the role granted by the source DN of a project
+	ProjectDefaultRole string `json:"projectDefaultRole"`
+
+	RBAC RBACConfig `json:"rbac"`
+}
+
 type LdapConfig struct {
-	UserBase            string
-	GroupBase           string
-	AppGroupBase        string
-	CustomerGroupBase   string
-	OpsGroupBase        string
-	AdminGroupBase      string
-	Host                string
-	Port                int
-	UseSSL              bool
-	StartTLS            bool
-	SkipTLSVerification bool
-	BindDN              string
-	BindPassword        string
-	UserFilter          string
-	UserKey             string
-	GroupFilter         string
-	Attributes          []string
-}
-
-func LoadConfig() LdapConfig {
+	UserBase string   `json:"userBase"`
+	Hosts    []string `json:"servers"`
+	Port     int      `json:"port"`
+	// BindMethod is simple, binding with BindDN and BindPassword, external,
+	// authenticating with the TLS client certificate, or gssapi with Kerberos
+	BindMethod   string         `json:"bindMethod"`
+	Kerberos     KerberosConfig `json:"kerberos"`
+	BindDN       string         `json:"bindDN"`
+	BindPassword string         `json:"bindPassword"`
+	// BindPasswordFile is read instead of BindPassword when set, typically a
+	// mounted Secret, and watched for changes along with the configuration file
+	BindPasswordFile string `json:"bindPasswordFile"`
+	// UserFilter restricts the user entries, %s standing for any value so that
+	// the login filter of kubi, such as (cn=%s), can be reused
+	UserFilter string `json:"userFilter"`
+	// UserKey is the ID attribute when Attributes.ID is not set, for compatibility
+	UserKey    string         `json:"userKey"`
+	Attributes UserAttributes `json:"attributes"`
+
+	UseSSL   bool `json:"useSSL"`
+	StartTLS bool `json:"startTLS"`
+	// SkipTLSVerification accepts any server certificate, for tests only
+	SkipTLSVerification bool `json:"skipTLSVerification"`
+	// CAFile is a PEM bundle of the CAs trusted to sign the server certificates,
+	// the system ones when empty
+	CAFile string `json:"caFile"`
+	// CertFile and KeyFile are the client certificate presented to the servers
+	CertFile      string   `json:"certFile"`
+	KeyFile       string   `json:"keyFile"`
+	MinTLSVersion string   `json:"minTLSVersion"`
+	CipherSuites  []string `json:"cipherSuites"`
+
+	// GroupMaxDepth bounds the expansion of nested groups, 0 only reads direct members
+	GroupMaxDepth int `json:"groupMaxDepth"`
+	// UseMatchingRuleInChain resolves nested groups server side with the Active Directory
+	// LDAP_MATCHING_RULE_IN_CHAIN filter instead of expanding them recursively
+	UseMatchingRuleInChain bool `json:"matchingRuleInChain"`
+	// PageSize is the Simple Paged Results page size of subtree searches, 0 disables paging
+	PageSize int `json:"pageSize"`
+	// BatchSize is the number of member DNs resolved by a single user search
+	BatchSize int `json:"batchSize"`
+
+	// PoolSize is the maximum number of concurrent connections to LDAP
+	PoolSize    int             `json:"poolSize"`
+	DialTimeout metav1.Duration `json:"dialTimeout"`
+	ReadTimeout metav1.Duration `json:"readTimeout"`
+	// Retries is the number of times an operation failing on a network error is retried,
+	// waiting RetryBackoff, then doubling it, between each attempt
+	Retries      int             `json:"retries"`
+	RetryBackoff metav1.Duration `json:"retryBackoff"`
+}
+
+// UserAttributes maps the fields of a user to LDAP attributes. Each field lists
+// candidate attributes, the first one set on the entry is used.
+type UserAttributes struct {
+	ID          []string `json:"id"`
+	Username    []string `json:"username"`
+	DisplayName []string `json:"displayName"`
+	Mail        []string `json:"mail"`
+	// Extra attributes are copied as is to the members
+	Extra []string `json:"extra"`
+}
+
+// personFilter matches the user entries of OpenLDAP and Active Directory
+const personFilter = "(|(objectClass=person)(objectClass=organizationalPerson))"
 
+// UserSearchFilter returns the filter of the user searches, UserFilter
+// restricting the person entries
+func (c LdapConfig) UserSearchFilter() string {
+	filter := strings.ReplaceAll(c.UserFilter, "%s", "*")
+	if filter == "" {
+		return personFilter
+	}
+	return "(&" + personFilter + filter + ")"
+}
+
+// Bind methods of the LDAP connections
+const (
+	BindSimple   = "simple"
+	BindExternal = "external"
+	BindGSSAPI   = "gssapi"
+)
+
+// KerberosConfig holds the credentials of the GSSAPI bind
+type KerberosConfig struct {
+	Username   string `json:"username"`
+	Realm      string `json:"realm"`
+	KeytabFile string `json:"keytabFile"`
+	ConfigFile string `json:"configFile"`
+	// ServicePrincipal is the SPN of the servers, ldap/<server host> when empty
+	ServicePrincipal string `json:"servicePrincipal"`
+}
+
+// DefaultConfig returns the configuration used when nothing is set
+func DefaultConfig() Config {
+	return Config{
+		APIVersion: ConfigAPIVersion,
+		LDAP: LdapConfig{
+			Port:          389,
+			BindMethod:    BindSimple,
+			Kerberos:      KerberosConfig{ConfigFile: "/etc/krb5.conf"},
+			MinTLSVersion: "1.2",
+			UserFilter:    "(cn=%s)",
+			Attributes: UserAttributes{
+				// Username and Mail keep the attributes read before the mapping
+				// existed, so that existing members are not rewritten
+				Username:    []string{"cn"},
+				DisplayName: []string{"displayName", "cn"},
+				Mail:        []string{"mail"},
+			},
+			GroupMaxDepth: 10,
+			PageSize:      500,
+			BatchSize:     50,
+			PoolSize:      4,
+			DialTimeout:   metav1.Duration{Duration: 10 * time.Second},
+			ReadTimeout:   metav1.Duration{Duration: 30 * time.Second},
+			Retries:       3,
+			RetryBackoff:  metav1.Duration{Duration: time.Second},
+		},
+		ProjectDefaultRole: "member",
+		RBAC: RBACConfig{
+			SubjectAttribute: "mail",
+		},
+	}
+}
+
+// LoadConfig reads the configuration file at path, when set, then applies the
+// environment variables over it. Every parse and validation error is reported
+// in the returned aggregate, along with the configuration as far as it was read.
+func LoadConfig(path string) (Config, error) {
 	env := os.Getenv("GO_DOT_ENV")
 	if env != "" {
 		filePath := filepath.Join("dev", ".env")
@@ -41,53 +181,311 @@ func LoadConfig() LdapConfig {
 		}
 	}
 
-	ldapPort, errLdapPort := strconv.Atoi(getEnv("LDAP_PORT", "389"))
-	Checkf(errLdapPort, "Invalid LDAP_PORT, must be an integer")
+	config := DefaultConfig()
+	var errs []error
 
-	useSSL, errLdapSSL := strconv.ParseBool(getEnv("LDAP_USE_SSL", "false"))
-	Checkf(errLdapSSL, "Invalid LDAP_USE_SSL, must be a boolean")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, err
+		}
+		if err := yaml.UnmarshalStrict(data, &config); err != nil {
+			errs = append(errs, fmt.Errorf("%s : %w", path, err))
+		}
+	}
 
-	skipTLSVerification, errSkipTLS := strconv.ParseBool(getEnv("LDAP_SKIP_TLS_VERIFICATION", "true"))
-	Checkf(errSkipTLS, "Invalid LDAP_SKIP_TLS_VERIFICATION, must be a boolean")
+	errs = append(errs, config.applyEnv()...)
 
-	startTLS, errStartTLS := strconv.ParseBool(getEnv("LDAP_START_TLS", "false"))
-	Checkf(errStartTLS, "Invalid LDAP_START_TLS, must be a boolean")
+	if config.LDAP.BindPasswordFile != "" {
+		password, err := os.ReadFile(config.LDAP.BindPasswordFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ldap.bindPasswordFile : %w", err))
+		}
+		config.LDAP.BindPassword = strings.TrimRight(string(password), "\r\n")
+	}
 
-	if len(os.Getenv("LDAP_PORT")) > 0 {
-		envLdapPort, err := strconv.Atoi(os.Getenv("LDAP_PORT"))
-		Check(err)
-		ldapPort = envLdapPort
-		if ldapPort == 389 && os.Getenv("LDAP_SKIP_TLS") == "false" {
-			skipTLSVerification = false
+	if len(config.LDAP.Attributes.ID) == 0 {
+		if config.LDAP.UserKey != "" {
+			config.LDAP.Attributes.ID = []string{config.LDAP.UserKey}
+		} else {
+			config.LDAP.Attributes.ID = []string{"sAMAccountName", "uid"}
 		}
-		if ldapPort == 636 && os.Getenv("LDAP_SKIP_TLS") == "false" {
-			skipTLSVerification = false
-			useSSL = true
+	}
+	if config.ClusterRoles == nil {
+		config.ClusterRoles = legacyClusterRoles()
+	}
+	// Bindings are only generated for the roles mapped by the operator
+	if config.RBAC.ProjectRoles == nil {
+		config.RBAC.ProjectRoles = map[string]string{}
+	}
+	if config.RBAC.ClusterRoles == nil {
+		config.RBAC.ClusterRoles = map[string]string{}
+	}
+
+	errs = append(errs, config.Validate()...)
+	return config, utilerrors.NewAggregate(errs)
+}
+
+// applyEnv overrides the configuration with the environment variables that are set
+func (c *Config) applyEnv() (errs []error) {
+	vars := []struct {
+		name  string
+		apply func(value string) error
+	}{
+		{"LDAP_USERBASE", setString(&c.LDAP.UserBase)},
+		{"LDAP_SERVER", setList(&c.LDAP.Hosts)},
+		{"LDAP_PORT", setInt(&c.LDAP.Port)},
+		{"LDAP_USE_SSL", setBool(&c.LDAP.UseSSL)},
+		{"LDAP_START_TLS", setBool(&c.LDAP.StartTLS)},
+		{"LDAP_SKIP_TLS_VERIFICATION", setBool(&c.LDAP.SkipTLSVerification)},
+		{"LDAP_CA_FILE", setString(&c.LDAP.CAFile)},
+		{"LDAP_CERT_FILE", setString(&c.LDAP.CertFile)},
+		{"LDAP_KEY_FILE", setString(&c.LDAP.KeyFile)},
+		{"LDAP_TLS_MIN_VERSION", setString(&c.LDAP.MinTLSVersion)},
+		{"LDAP_TLS_CIPHER_SUITES", setList(&c.LDAP.CipherSuites)},
+		{"LDAP_BIND_METHOD", setString(&c.LDAP.BindMethod)},
+		{"LDAP_KRB5_USERNAME", setString(&c.LDAP.Kerberos.Username)},
+		{"LDAP_KRB5_REALM", setString(&c.LDAP.Kerberos.Realm)},
+		{"LDAP_KRB5_KEYTAB", setString(&c.LDAP.Kerberos.KeytabFile)},
+		{"LDAP_KRB5_CONFIG", setString(&c.LDAP.Kerberos.ConfigFile)},
+		{"LDAP_KRB5_SPN", setString(&c.LDAP.Kerberos.ServicePrincipal)},
+		{"LDAP_BINDDN", setString(&c.LDAP.BindDN)},
+		{"LDAP_PASSWD", setString(&c.LDAP.BindPassword)},
+		{"LDAP_PASSWD_FILE", setString(&c.LDAP.BindPasswordFile)},
+		{"LDAP_USERFILTER", setString(&c.LDAP.UserFilter)},
+		{"LDAP_USERKEY", setString(&c.LDAP.UserKey)},
+		{"LDAP_ATTR_ID", setList(&c.LDAP.Attributes.ID)},
+		{"LDAP_ATTR_USERNAME", setList(&c.LDAP.Attributes.Username)},
+		{"LDAP_ATTR_DISPLAY_NAME", setList(&c.LDAP.Attributes.DisplayName)},
+		{"LDAP_ATTR_MAIL", setList(&c.LDAP.Attributes.Mail)},
+		{"LDAP_ATTR_EXTRA", setList(&c.LDAP.Attributes.Extra)},
+		{"LDAP_GROUP_MAX_DEPTH", setInt(&c.LDAP.GroupMaxDepth)},
+		{"LDAP_MATCHING_RULE_IN_CHAIN", setBool(&c.LDAP.UseMatchingRuleInChain)},
+		{"LDAP_PAGE_SIZE", setInt(&c.LDAP.PageSize)},
+		{"LDAP_BATCH_SIZE", setInt(&c.LDAP.BatchSize)},
+		{"LDAP_POOL_SIZE", setInt(&c.LDAP.PoolSize)},
+		{"LDAP_DIAL_TIMEOUT", setDuration(&c.LDAP.DialTimeout)},
+		{"LDAP_READ_TIMEOUT", setDuration(&c.LDAP.ReadTimeout)},
+		{"LDAP_RETRIES", setInt(&c.LDAP.Retries)},
+		{"LDAP_RETRY_BACKOFF", setDuration(&c.LDAP.RetryBackoff)},
+		{"CLUSTER_ROLES", func(value string) (err error) { c.ClusterRoles, err = ParseRoles([]byte(value)); return }},
+		{"PROJECT_DEFAULT_ROLE", setString(&c.ProjectDefaultRole)},
+		{"RBAC_SUBJECT_ATTRIBUTE", setString(&c.RBAC.SubjectAttribute)},
+		{"RBAC_PROJECT_ROLES", setMap(&c.RBAC.ProjectRoles)},
+		{"RBAC_CLUSTER_ROLES", setMap(&c.RBAC.ClusterRoles)},
+	}
+
+	for _, v := range vars {
+		if value, ok := os.LookupEnv(v.name); ok && value != "" {
+			if err := v.apply(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s : %w", v.name, err))
+			}
 		}
 	}
 
-	ldapUserFilter := getEnv("LDAP_USERFILTER", "(cn=%s)")
+	// LDAP_SKIP_TLS is the historical name of LDAP_SKIP_TLS_VERIFICATION
+	if value := os.Getenv("LDAP_SKIP_TLS"); value != "" && os.Getenv("LDAP_SKIP_TLS_VERIFICATION") == "" {
+		klog.Warning("LDAP_SKIP_TLS is deprecated, use LDAP_SKIP_TLS_VERIFICATION")
+		if err := setBool(&c.LDAP.SkipTLSVerification)(value); err != nil {
+			errs = append(errs, fmt.Errorf("LDAP_SKIP_TLS : %w", err))
+		}
+	}
 
-	ldapConfig := LdapConfig{
-		UserBase:            os.Getenv("LDAP_USERBASE"),
-		UserKey:             os.Getenv("LDAP_USERKEY"),
-		GroupBase:           os.Getenv("LDAP_GROUPBASE"),
-		AppGroupBase:        os.Getenv("LDAP_APP_GROUPBASE"),
-		CustomerGroupBase:   os.Getenv("LDAP_CUSTOMER_OPS_GROUPBASE"),
-		OpsGroupBase:        os.Getenv("LDAP_OPS_GROUPBASE"),
-		AdminGroupBase:      os.Getenv("LDAP_ADMINS_GROUPBASE"),
-		Host:                os.Getenv("LDAP_SERVER"),
-		Port:                ldapPort,
-		UseSSL:              useSSL,
-		StartTLS:            startTLS,
-		SkipTLSVerification: skipTLSVerification,
-		BindDN:              os.Getenv("LDAP_BINDDN"),
-		BindPassword:        os.Getenv("LDAP_PASSWD"),
-		UserFilter:          ldapUserFilter,
-		GroupFilter:         "(member=%s)",
-		Attributes:          []string{"givenName", "sn", "mail", "uid", "cn", "userPrincipalName"},
+	// 636 is the LDAPS port, StartTLS being the alternative on the plain port
+	if c.LDAP.Port == 636 && !c.LDAP.StartTLS {
+		c.LDAP.UseSSL = true
+	}
+	return
+}
+
+// Validate returns every inconsistency of the configuration
+func (c Config) Validate() (errs []error) {
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s : %s", field, fmt.Sprintf(format, args...)))
 	}
 
-	return ldapConfig
+	if c.APIVersion != ConfigAPIVersion {
+		invalid("apiVersion", "unsupported version %q, must be %s", c.APIVersion, ConfigAPIVersion)
+	}
 
+	ldap := c.LDAP
+	if len(ldap.Hosts) == 0 {
+		invalid("ldap.servers", "at least one server is required")
+	}
+	if ldap.Port < 1 || ldap.Port > 65535 {
+		invalid("ldap.port", "%d is not a valid port", ldap.Port)
+	}
+	if ldap.UseSSL && ldap.StartTLS {
+		invalid("ldap.startTLS", "cannot be used with useSSL")
+	}
+	attributes := []struct {
+		field string
+		names []string
+	}{
+		{"ldap.attributes.id", ldap.Attributes.ID},
+		{"ldap.attributes.username", ldap.Attributes.Username},
+		{"ldap.attributes.displayName", ldap.Attributes.DisplayName},
+		{"ldap.attributes.mail", ldap.Attributes.Mail},
+	}
+	for _, attribute := range attributes {
+		if len(attribute.names) == 0 {
+			invalid(attribute.field, "at least one attribute is required")
+		}
+	}
+	for _, names := range [][]string{ldap.Attributes.ID, ldap.Attributes.Username, ldap.Attributes.DisplayName, ldap.Attributes.Mail, ldap.Attributes.Extra} {
+		for _, name := range names {
+			if !attributeName.MatchString(name) {
+				invalid("ldap.attributes", "invalid attribute name %q", name)
+			}
+		}
+	}
+	if _, err := goldap.CompileFilter(ldap.UserSearchFilter()); err != nil {
+		invalid("ldap.userFilter", "%s", err)
+	}
+
+	switch ldap.BindMethod {
+	case BindSimple:
+	case BindExternal:
+		if ldap.CertFile == "" {
+			invalid("ldap.bindMethod", "external requires a client certificate, ldap.certFile and ldap.keyFile")
+		}
+		if !ldap.UseSSL && !ldap.StartTLS {
+			invalid("ldap.bindMethod", "external requires useSSL or startTLS")
+		}
+	case BindGSSAPI:
+		if ldap.Kerberos.Username == "" {
+			invalid("ldap.kerberos.username", "is required by the gssapi bind method")
+		}
+		files := []struct{ field, path string }{
+			{"ldap.kerberos.keytabFile", ldap.Kerberos.KeytabFile},
+			{"ldap.kerberos.configFile", ldap.Kerberos.ConfigFile},
+		}
+		for _, file := range files {
+			if file.path == "" {
+				invalid(file.field, "is required by the gssapi bind method")
+			} else if _, err := os.Stat(file.path); err != nil {
+				invalid(file.field, "%s", err)
+			}
+		}
+	default:
+		invalid("ldap.bindMethod", "unknown method %q, must be one of simple, external or gssapi", ldap.BindMethod)
+	}
+	if _, err := ldap.TLSConfig(); err != nil {
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			errs = append(errs, agg.Errors()...)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	if ldap.GroupMaxDepth < 0 {
+		invalid("ldap.groupMaxDepth", "must be positive")
+	}
+	if ldap.PageSize < 0 {
+		invalid("ldap.pageSize", "must be positive")
+	}
+	if ldap.BatchSize < 0 {
+		invalid("ldap.batchSize", "must be positive")
+	}
+	if ldap.PoolSize < 1 {
+		invalid("ldap.poolSize", "must be at least 1")
+	}
+	if ldap.DialTimeout.Duration <= 0 {
+		invalid("ldap.dialTimeout", "must be positive")
+	}
+	if ldap.ReadTimeout.Duration <= 0 {
+		invalid("ldap.readTimeout", "must be positive")
+	}
+	if ldap.Retries < 0 {
+		invalid("ldap.retries", "must be positive")
+	}
+	if ldap.RetryBackoff.Duration < 0 {
+		invalid("ldap.retryBackoff", "must be positive")
+	}
+
+	if err := c.ClusterRoles.Validate(); err != nil {
+		invalid("clusterRoles", "%s", err)
+	}
+	if c.ProjectDefaultRole == "" {
+		invalid("projectDefaultRole", "is required")
+	}
+
+	switch c.RBAC.SubjectAttribute {
+	case "mail", "uid", "username", "dn":
+	default:
+		invalid("rbac.subjectAttribute", "unknown attribute %q, must be one of mail, uid, username or dn", c.RBAC.SubjectAttribute)
+	}
+	return
+}
+
+// Redacted returns a copy of the configuration without its secrets, for display
+func (c Config) Redacted() Config {
+	if c.LDAP.BindPassword != "" {
+		c.LDAP.BindPassword = redacted
+	}
+	return c
+}
+
+func setString(field *string) func(string) error {
+	return func(value string) error {
+		*field = value
+		return nil
+	}
+}
+
+func setInt(field *int) func(string) error {
+	return func(value string) (err error) {
+		*field, err = strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		return
+	}
+}
+
+func setList(field *[]string) func(string) error {
+	return func(value string) error {
+		*field = splitList(value)
+		return nil
+	}
+}
+
+func setBool(field *bool) func(string) error {
+	return func(value string) (err error) {
+		*field, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		return
+	}
+}
+
+func setDuration(field *metav1.Duration) func(string) error {
+	return func(value string) (err error) {
+		field.Duration, err = time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration")
+		}
+		return
+	}
+}
+
+func setMap(field *map[string]string) func(string) error {
+	return func(value string) error {
+		parsed := map[string]string{}
+		if err := yaml.UnmarshalStrict([]byte(value), &parsed); err != nil {
+			return fmt.Errorf("must be a YAML or JSON map : %w", err)
+		}
+		*field = parsed
+		return nil
+	}
+}
+
+// splitList splits a comma separated list, ignoring blank items
+func splitList(value string) (items []string) {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
 }
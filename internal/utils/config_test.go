@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the variables read by the configuration for the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, variable := range os.Environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		for _, prefix := range []string{"LDAP_", "CLUSTER_ROLES", "PROJECT_DEFAULT_ROLE", "RBAC_", "GO_DOT_ENV"} {
+			if strings.HasPrefix(name, prefix) {
+				t.Setenv(name, "")
+			}
+		}
+	}
+}
+
+// validConfig returns the default configuration completed with the fields
+// required by Validate
+func validConfig() Config {
+	config := DefaultConfig()
+	config.LDAP.Hosts = []string{"ldap.example.com"}
+	config.LDAP.Attributes.ID = []string{"uid"}
+	return config
+}
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   []string
+	}{
+		{"valid", func(*Config) {}, nil},
+		{"api version", func(c *Config) { c.APIVersion = "v0" }, []string{"apiVersion"}},
+		{"no server", func(c *Config) { c.LDAP.Hosts = nil }, []string{"ldap.servers"}},
+		{"port", func(c *Config) { c.LDAP.Port = 0 }, []string{"ldap.port"}},
+		{"ssl and starttls", func(c *Config) { c.LDAP.UseSSL, c.LDAP.StartTLS = true, true }, []string{"ldap.startTLS"}},
+		{"no username attribute", func(c *Config) { c.LDAP.Attributes.Username = nil }, []string{"ldap.attributes.username"}},
+		{"attribute name", func(c *Config) { c.LDAP.Attributes.Extra = []string{"not an attribute"} }, []string{"ldap.attributes"}},
+		{"user filter", func(c *Config) { c.LDAP.UserFilter = "(cn=%s" }, []string{"ldap.userFilter"}},
+		{"bind method", func(c *Config) { c.LDAP.BindMethod = "anonymous" }, []string{"ldap.bindMethod"}},
+		{"external without tls", func(c *Config) { c.LDAP.BindMethod = BindExternal }, []string{"ldap.bindMethod", "ldap.bindMethod"}},
+		{"gssapi", func(c *Config) {
+			c.LDAP.BindMethod = BindGSSAPI
+			c.LDAP.Kerberos.ConfigFile = ""
+		}, []string{"ldap.kerberos.username", "ldap.kerberos.keytabFile", "ldap.kerberos.configFile"}},
+		{"tls errors", func(c *Config) {
+			c.LDAP.MinTLSVersion = "0.9"
+			c.LDAP.CertFile = "client.crt"
+		}, []string{"ldap.minTLSVersion", "ldap.certFile and ldap.keyFile must be set together"}},
+		{"negative sizes", func(c *Config) {
+			c.LDAP.GroupMaxDepth, c.LDAP.PageSize, c.LDAP.BatchSize, c.LDAP.PoolSize = -1, -1, -1, 0
+		}, []string{"ldap.groupMaxDepth", "ldap.pageSize", "ldap.batchSize", "ldap.poolSize"}},
+		{"durations", func(c *Config) {
+			c.LDAP.DialTimeout.Duration, c.LDAP.ReadTimeout.Duration, c.LDAP.RetryBackoff.Duration = 0, 0, -time.Second
+		}, []string{"ldap.dialTimeout", "ldap.readTimeout", "ldap.retryBackoff"}},
+		{"cluster roles", func(c *Config) { c.ClusterRoles = Roles{{Name: "Admin"}, {Name: "Admin"}} }, []string{"clusterRoles"}},
+		{"project default role", func(c *Config) { c.ProjectDefaultRole = "" }, []string{"projectDefaultRole"}},
+		{"subject attribute", func(c *Config) { c.RBAC.SubjectAttribute = "cn" }, []string{"rbac.subjectAttribute"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := validConfig()
+			test.modify(&config)
+
+			var fields []string
+			for _, err := range config.Validate() {
+				fields = append(fields, strings.SplitN(err.Error(), " : ", 2)[0])
+			}
+			if !reflect.DeepEqual(fields, test.want) {
+				t.Errorf("Validate() reported %v, want %v", fields, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(Config) bool
+		wantErr bool
+	}{
+		{
+			name: "overrides the file",
+			env:  map[string]string{"LDAP_SERVER": "a.example.com, b.example.com", "LDAP_PORT": "3389", "LDAP_ATTR_USERNAME": "uid"},
+			check: func(c Config) bool {
+				return reflect.DeepEqual(c.LDAP.Hosts, []string{"a.example.com", "b.example.com"}) && c.LDAP.Port == 3389 && reflect.DeepEqual(c.LDAP.Attributes.Username, []string{"uid"})
+			},
+		},
+		{
+			name:  "empty variables are ignored",
+			env:   map[string]string{"LDAP_SERVER": "", "LDAP_USERBASE": ""},
+			check: func(c Config) bool { return c.LDAP.Hosts[0] == "file.example.com" && c.LDAP.UserBase == "ou=file" },
+		},
+		{
+			name:  "ldaps port",
+			env:   map[string]string{"LDAP_PORT": "636"},
+			check: func(c Config) bool { return c.LDAP.UseSSL },
+		},
+		{
+			name:  "ldaps port with starttls",
+			env:   map[string]string{"LDAP_PORT": "636", "LDAP_START_TLS": "true"},
+			check: func(c Config) bool { return !c.LDAP.UseSSL && c.LDAP.StartTLS },
+		},
+		{
+			name:  "deprecated skip tls",
+			env:   map[string]string{"LDAP_SKIP_TLS": "true"},
+			check: func(c Config) bool { return c.LDAP.SkipTLSVerification },
+		},
+		{
+			name:  "skip tls verification wins over the deprecated name",
+			env:   map[string]string{"LDAP_SKIP_TLS": "true", "LDAP_SKIP_TLS_VERIFICATION": "false"},
+			check: func(c Config) bool { return !c.LDAP.SkipTLSVerification },
+		},
+		{
+			name: "maps and durations",
+			env:  map[string]string{"RBAC_CLUSTER_ROLES": `{"Admin": "cluster-admin"}`, "LDAP_DIAL_TIMEOUT": "5s"},
+			check: func(c Config) bool {
+				return c.RBAC.ClusterRoles["Admin"] == "cluster-admin" && c.LDAP.DialTimeout.Duration == 5*time.Second
+			},
+		},
+		{
+			name:    "invalid value",
+			env:     map[string]string{"LDAP_PORT": "ldaps"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			config := validConfig()
+			config.LDAP.Hosts = []string{"file.example.com"}
+			config.LDAP.UserBase = "ou=file"
+
+			errs := config.applyEnv()
+			if (len(errs) > 0) != test.wantErr {
+				t.Errorf("applyEnv() errors = %v, want errors %v", errs, test.wantErr)
+			}
+			if test.check != nil && !test.check(config) {
+				t.Errorf("unexpected configuration %+v", config.LDAP)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	passwordFile := writeFile(t, "password", "from-file\n")
+	configFile := writeFile(t, "config.yaml", `
+apiVersion: kubi-members/v1
+ldap:
+  servers: [file.example.com]
+  userBase: ou=file
+  bindPassword: from-config
+rbac:
+  clusterRoles:
+    Admin: cluster-admin
+`)
+
+	t.Run("file", func(t *testing.T) {
+		clearEnv(t)
+		config, err := LoadConfig(configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.LDAP.UserBase != "ou=file" || config.LDAP.BindPassword != "from-config" {
+			t.Errorf("unexpected configuration %+v", config.LDAP)
+		}
+		if !reflect.DeepEqual(config.LDAP.Attributes.ID, []string{"sAMAccountName", "uid"}) {
+			t.Errorf("id attributes %v, want the defaults", config.LDAP.Attributes.ID)
+		}
+		if len(config.RBAC.ProjectRoles) != 0 || config.RBAC.ClusterRoles["Admin"] != "cluster-admin" {
+			t.Errorf("unexpected rbac configuration %+v", config.RBAC)
+		}
+	})
+
+	t.Run("environment over file", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("LDAP_USERBASE", "ou=env")
+		t.Setenv("LDAP_USERKEY", "employeeNumber")
+		config, err := LoadConfig(configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.LDAP.UserBase != "ou=env" {
+			t.Errorf("user base %q, want the environment one", config.LDAP.UserBase)
+		}
+		if !reflect.DeepEqual(config.LDAP.Attributes.ID, []string{"employeeNumber"}) {
+			t.Errorf("id attributes %v, want the user key", config.LDAP.Attributes.ID)
+		}
+	})
+
+	t.Run("bind password file", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("LDAP_PASSWD", "from-env")
+		t.Setenv("LDAP_PASSWD_FILE", passwordFile)
+		config, err := LoadConfig(configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.LDAP.BindPassword != "from-file" {
+			t.Errorf("bind password %q, want the content of the file", config.LDAP.BindPassword)
+		}
+	})
+
+	t.Run("missing bind password file", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("LDAP_PASSWD_FILE", filepath.Join(t.TempDir(), "missing"))
+		if _, err := LoadConfig(configFile); err == nil || !strings.Contains(err.Error(), "ldap.bindPasswordFile") {
+			t.Errorf("LoadConfig() error = %v, want the bindPasswordFile error", err)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		clearEnv(t)
+		path := writeFile(t, "config.yaml", "apiVersion: kubi-members/v1\nldap:\n  servers: [a]\n  unknown: true\n")
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "unknown") {
+			t.Errorf("LoadConfig() error = %v, want the unknown field", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		clearEnv(t)
+		if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+			t.Error("LoadConfig() succeeded without its file")
+		}
+	})
+}
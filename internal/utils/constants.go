@@ -5,20 +5,11 @@ const (
 
 	CouldNotList = "Could not list resources %s"
 
+	// ClusterMembersKey is the workqueue key used to reconcile cluster members,
+	// it cannot collide with a project name
+	ClusterMembersKey = "#clustermembers"
 
+	// ProjectRolesAnnotation maps LDAP groups to roles in a project, as a YAML
+	// or JSON list of {name, groupDNs, priority}
+	ProjectRolesAnnotation = "members.cagip.github.com/roles"
 )
-
-type ClusterRole int
-
-const (
-	CustomerRole 	  ClusterRole	= iota
-	AppRole
-	OpsRole
-	AdminRole
-)
-
-func (c ClusterRole) String() string{
-	return  [...]string{"CustomerOps", "AppOps", "ClusterOps", "Admin"}[c]
-}
-
-
@@ -0,0 +1,35 @@
+package utils
+
+const (
+	// ManagedByLabel marks the bindings generated by kubi-members
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedBy      = "kubi-members"
+)
+
+// RBACConfig drives the generation of RoleBindings from project members and of
+// ClusterRoleBindings from cluster members
+type RBACConfig struct {
+	// SubjectAttribute is the member field used as user name in the bindings:
+	// mail, uid, username or dn
+	SubjectAttribute string `json:"subjectAttribute"`
+	// ProjectRoles maps the role of a project member to the ClusterRole bound
+	// in the namespace, members whose role is not mapped get no binding
+	ProjectRoles map[string]string `json:"projectRoles"`
+	// ClusterRoles maps the role of a cluster member to the ClusterRole bound
+	// cluster wide, members whose role is not mapped get no binding
+	ClusterRoles map[string]string `json:"clusterRoles"`
+}
+
+// Subject returns the attribute of a member used as user name in its bindings
+func (c RBACConfig) Subject(uid, dn, username, mail string) string {
+	switch c.SubjectAttribute {
+	case "uid":
+		return uid
+	case "username":
+		return username
+	case "dn":
+		return dn
+	default:
+		return mail
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// secretFields are the fields whose values are never logged
+var secretFields = map[string]bool{"ldap.bindPassword": true}
+
+// WatchConfig loads the configuration at path again each interval, the bind
+// password file included, and calls apply when it changed. Invalid
+// configurations and failed applies are logged and retried on the next tick,
+// the current configuration being kept meanwhile.
+func WatchConfig(path string, current Config, interval time.Duration, stopCh <-chan struct{}, apply func(Config) error) {
+	lastErr := ""
+	wait.Until(func() {
+		config, err := LoadConfig(path)
+		if err != nil {
+			if err.Error() != lastErr {
+				klog.Errorf("Could not reload configuration, keeping the current one : %s", err)
+				lastErr = err.Error()
+			}
+			return
+		}
+
+		if equality.Semantic.DeepEqual(config, current) {
+			return
+		}
+		changes := ConfigChanges(current, config)
+		if err := apply(config); err != nil {
+			if err.Error() != lastErr {
+				klog.Errorf("Could not apply configuration changes %v, keeping the current configuration : %s", changes, err)
+				lastErr = err.Error()
+			}
+			return
+		}
+
+		klog.Infof("Configuration reloaded, %d changes : %v", len(changes), changes)
+		current = config
+		lastErr = ""
+	}, interval, stopCh)
+}
+
+// ConfigChanges describes the fields that differ between previous and config,
+// with their values except for secrets
+func ConfigChanges(previous, config Config) []string {
+	before, after := flatten(previous), flatten(config)
+
+	var changes []string
+	for field, value := range after {
+		old, found := before[field]
+		switch {
+		case !found:
+			changes = append(changes, fmt.Sprintf("%s added", field))
+		case old == value:
+		case secretFields[field]:
+			changes = append(changes, fmt.Sprintf("%s changed", field))
+		default:
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, old, value))
+		}
+	}
+	for field := range before {
+		if _, found := after[field]; !found {
+			changes = append(changes, fmt.Sprintf("%s removed", field))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// flatten maps the path of each scalar field of config to its JSON value,
+// lists being kept whole
+func flatten(config Config) map[string]string {
+	fields := map[string]string{}
+	data, _ := json.Marshal(config)
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fields
+	}
+
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		if object, ok := value.(map[string]interface{}); ok {
+			for key, child := range object {
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+				walk(key, child)
+			}
+			return
+		}
+		encoded, _ := json.Marshal(value)
+		fields[prefix] = string(encoded)
+	}
+	walk("", tree)
+	return fields
+}
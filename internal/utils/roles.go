@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Role grants access, cluster wide or to a project, to the members of its LDAP
+// groups. A user member of several roles gets the one with the highest priority.
+type Role struct {
+	Name     string   `json:"name"`
+	GroupDNs []string `json:"groupDNs"`
+	Priority int      `json:"priority"`
+}
+
+// Roles is an ordered list of roles
+type Roles []Role
+
+// Get returns the role called name
+func (r Roles) Get(name string) (Role, bool) {
+	for _, role := range r {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// Names returns the name of every role, by decreasing priority
+func (r Roles) Names() []string {
+	sorted := make(Roles, len(r))
+	copy(sorted, r)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority > sorted[j].Priority })
+
+	names := make([]string, 0, len(sorted))
+	for _, role := range sorted {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// Validate checks that every role has a unique name
+func (r Roles) Validate() error {
+	seen := make(map[string]bool, len(r))
+	for i, role := range r {
+		if role.Name == "" {
+			return fmt.Errorf("role %d has no name", i)
+		}
+		if seen[role.Name] {
+			return fmt.Errorf("role %s is defined twice", role.Name)
+		}
+		seen[role.Name] = true
+	}
+	return nil
+}
+
+// ParseRoles reads a YAML or JSON list of roles
+func ParseRoles(data []byte) (roles Roles, err error) {
+	if err = yaml.UnmarshalStrict(data, &roles); err != nil {
+		return nil, err
+	}
+	return roles, roles.Validate()
+}
+
+// ProjectRoles returns the roles of a project: its source DN grants defaultRole
+// with priority 0, and the roles annotation of the project may add more groups
+// and roles
+func ProjectRoles(sourceDN string, annotations map[string]string, defaultRole string) (Roles, error) {
+	var roles Roles
+	if value, ok := annotations[ProjectRolesAnnotation]; ok {
+		parsed, err := ParseRoles([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation %s : %w", ProjectRolesAnnotation, err)
+		}
+		roles = parsed
+	}
+	if sourceDN == "" {
+		return roles, nil
+	}
+
+	for i, role := range roles {
+		if role.Name == defaultRole {
+			roles[i].GroupDNs = append(role.GroupDNs, sourceDN)
+			return roles, nil
+		}
+	}
+	return append(Roles{{Name: defaultRole, GroupDNs: []string{sourceDN}}}, roles...), nil
+}
+
+// legacyClusterRoles builds the four historical roles from their dedicated
+// environment variables, roles without group are kept so that they are reported
+func legacyClusterRoles() Roles {
+	roles := Roles{
+		{Name: "CustomerOps", Priority: 0},
+		{Name: "AppOps", Priority: 1},
+		{Name: "ClusterOps", Priority: 2},
+		{Name: "Admin", Priority: 3},
+	}
+	groups := []string{
+		os.Getenv("LDAP_CUSTOMER_OPS_GROUPBASE"),
+		os.Getenv("LDAP_APP_GROUPBASE"),
+		os.Getenv("LDAP_OPS_GROUPBASE"),
+		os.Getenv("LDAP_ADMINS_GROUPBASE"),
+	}
+	for i, group := range groups {
+		if group != "" {
+			roles[i].GroupDNs = []string{group}
+		}
+	}
+	return roles
+}
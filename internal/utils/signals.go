@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SetupSignalHandler returns a channel closed on SIGTERM or SIGINT.
+// A second signal terminates the program immediately.
+func SetupSignalHandler() <-chan struct{} {
+	stop := make(chan struct{})
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		close(stop)
+		<-c
+		os.Exit(1)
+	}()
+
+	return stop
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig builds the TLS configuration of the LDAPS and StartTLS connections.
+// The client certificate is read again on each handshake so that a renewed
+// certificate is used without reloading the configuration.
+func (c LdapConfig) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.SkipTLSVerification}
+	var errs []error
+
+	version, ok := tlsVersions[c.MinTLSVersion]
+	if !ok {
+		errs = append(errs, fmt.Errorf("ldap.minTLSVersion : unknown version %q, must be one of %s", c.MinTLSVersion, strings.Join(sortedKeys(tlsVersions), ", ")))
+	}
+	config.MinVersion = version
+
+	if len(c.CipherSuites) > 0 {
+		suites := map[string]uint16{}
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range c.CipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("ldap.cipherSuites : unknown or insecure cipher suite %q", name))
+			}
+			config.CipherSuites = append(config.CipherSuites, id)
+		}
+	}
+
+	if c.CAFile != "" {
+		config.RootCAs = x509.NewCertPool()
+		if pem, err := os.ReadFile(c.CAFile); err != nil {
+			errs = append(errs, fmt.Errorf("ldap.caFile : %w", err))
+		} else if !config.RootCAs.AppendCertsFromPEM(pem) {
+			errs = append(errs, fmt.Errorf("ldap.caFile : no PEM certificate found in %s", c.CAFile))
+		}
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("ldap.certFile and ldap.keyFile must be set together"))
+	} else if c.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("ldap.certFile : %w", err))
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to load client certificate : %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return config, nil
+}
+
+func sortedKeys(m map[string]uint16) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
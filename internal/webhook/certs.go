@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ca-gip/kubi-members/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// certificateValidity is the validity of the generated CA and certificate
+const certificateValidity = 10 * 365 * 24 * time.Hour
+
+// EnsureCertificate returns the serving certificate of the webhook stored in
+// the Secret namespace/secretName, generated along with a self-signed CA for
+// the Service namespace/service when the Secret does not exist. The CA is set
+// as caBundle of the CRDs, so that the webhook needs no manual TLS setup.
+// Every replica shares the certificate of the Secret.
+func EnsureCertificate(ctx context.Context, kubeClient kubernetes.Interface, crdClient apiextensionsclientset.Interface, namespace string, secretName string, service string) (tls.Certificate, error) {
+	secrets := kubeClient.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(ctx, secretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		secret, err = generateSecret(namespace, secretName, service)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not generate webhook certificate : %w", err)
+		}
+		created, createErr := secrets.Create(ctx, secret, metav1.CreateOptions{})
+		switch {
+		case createErr == nil:
+			klog.Infof("Generated webhook certificate in Secret %s/%s", namespace, secretName)
+			secret = created
+		case errors.IsAlreadyExists(createErr):
+			// Another replica created it first
+			secret, err = secrets.Get(ctx, secretName, metav1.GetOptions{})
+		default:
+			err = createErr
+		}
+	}
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not get Secret %s/%s : %w", namespace, secretName, err)
+	}
+
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid certificate in Secret %s/%s : %w", namespace, secretName, err)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"conversion": map[string]interface{}{"webhook": map[string]interface{}{
+			"clientConfig": map[string]interface{}{"caBundle": secret.Data["ca.crt"]},
+		}}},
+	})
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	for _, name := range CRDs {
+		_, err := crdClient.ApiextensionsV1().CustomResourceDefinitions().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not set caBundle of %s : %w", name, err)
+		}
+	}
+	return cert, nil
+}
+
+// generateSecret returns a kubernetes.io/tls Secret holding a self-signed CA,
+// as ca.crt, and a certificate it signed for the DNS names of the Service
+func generateSecret(namespace string, secretName string, service string) (*corev1.Secret, error) {
+	now := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: utils.ManagedBy + "-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	host := service + "." + namespace + ".svc"
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{service, service + "." + namespace, host, host + ".cluster.local"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: namespace,
+			Labels:    map[string]string{utils.ManagedByLabel: utils.ManagedBy},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+			"ca.crt":                pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		},
+	}, nil
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEnsureCertificate(t *testing.T) {
+	var crds []runtime.Object
+	for _, name := range CRDs {
+		crds = append(crds, &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Conversion: &apiextensionsv1.CustomResourceConversion{
+					Strategy: apiextensionsv1.WebhookConverter,
+					Webhook:  &apiextensionsv1.WebhookConversion{ClientConfig: &apiextensionsv1.WebhookClientConfig{}},
+				},
+			},
+		})
+	}
+	kubeClient := kubefake.NewSimpleClientset()
+	crdClient := apiextensionsfake.NewSimpleClientset(crds...)
+	ctx := context.Background()
+
+	cert, err := EnsureCertificate(ctx, kubeClient, crdClient, "kube-system", "kubi-members-webhook-tls", "kubi-members")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets("kube-system").Get(ctx, "kubi-members-webhook-tls", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Secret not created : %s", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(secret.Data["ca.crt"]) {
+		t.Fatal("no CA in the Secret")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "kubi-members.kube-system.svc", Roots: roots}); err != nil {
+		t.Errorf("certificate not valid for the Service : %s", err)
+	}
+
+	for _, name := range CRDs {
+		crd, err := crdClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(crd.Spec.Conversion.Webhook.ClientConfig.CABundle, secret.Data["ca.crt"]) {
+			t.Errorf("caBundle of %s not set to the generated CA", name)
+		}
+	}
+
+	again, err := EnsureCertificate(ctx, kubeClient, crdClient, "kube-system", "kubi-members-webhook-tls", "kubi-members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Certificate[0], cert.Certificate[0]) {
+		t.Error("the certificate of the existing Secret was not reused")
+	}
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	v1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// ConversionPath is the path the CRDs send their ConversionReview to
+const ConversionPath = "/convert"
+
+// ServeConversion answers the ConversionReview requests of the API server,
+// converting ProjectMember and ClusterMember objects between v1 and v2
+func ServeConversion(w http.ResponseWriter, r *http.Request) {
+	review := apiextensionsv1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode ConversionReview : %s", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	response := &apiextensionsv1.ConversionResponse{UID: review.Request.UID, Result: metav1.Status{Status: metav1.StatusSuccess}}
+	for _, object := range review.Request.Objects {
+		converted, err := convert(object.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			klog.Errorf("Could not convert object to %s : %s", review.Request.DesiredAPIVersion, err)
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			break
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	review.Request = nil
+	review.Response = response
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		klog.Errorf("Could not write ConversionReview response : %s", err)
+	}
+}
+
+// convert returns raw, a ProjectMember or ClusterMember, in the desired version
+func convert(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+
+	var converted interface{}
+	switch {
+	case typeMeta.APIVersion == v1.SchemeGroupVersion.String() && desiredAPIVersion == v2.SchemeGroupVersion.String():
+		switch typeMeta.Kind {
+		case "ProjectMember":
+			in := &v1.ProjectMember{}
+			if err := json.Unmarshal(raw, in); err != nil {
+				return nil, err
+			}
+			converted = v2.ProjectMemberFromV1(in)
+		case "ClusterMember":
+			in := &v1.ClusterMember{}
+			if err := json.Unmarshal(raw, in); err != nil {
+				return nil, err
+			}
+			converted = v2.ClusterMemberFromV1(in)
+		}
+	case typeMeta.APIVersion == v2.SchemeGroupVersion.String() && desiredAPIVersion == v1.SchemeGroupVersion.String():
+		switch typeMeta.Kind {
+		case "ProjectMember":
+			in := &v2.ProjectMember{}
+			if err := json.Unmarshal(raw, in); err != nil {
+				return nil, err
+			}
+			converted = v2.ProjectMemberToV1(in)
+		case "ClusterMember":
+			in := &v2.ClusterMember{}
+			if err := json.Unmarshal(raw, in); err != nil {
+				return nil, err
+			}
+			converted = v2.ClusterMemberToV1(in)
+		}
+	}
+	if converted == nil {
+		return nil, fmt.Errorf("unsupported conversion of %s %s to %s", typeMeta.APIVersion, typeMeta.Kind, desiredAPIVersion)
+	}
+	return json.Marshal(converted)
+}
+
+// Serve serves the conversion webhook over TLS on addr until stopCh is closed
+func Serve(addr string, certFile string, keyFile string, stopCh <-chan struct{}) {
+	serve(&http.Server{Addr: addr}, certFile, keyFile, stopCh)
+}
+
+// ServeCertificate serves the conversion webhook like Serve with cert, see
+// EnsureCertificate
+func ServeCertificate(addr string, cert tls.Certificate, stopCh <-chan struct{}) {
+	serve(&http.Server{Addr: addr, TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}}, "", "", stopCh)
+}
+
+func serve(server *http.Server, certFile string, keyFile string, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ConversionPath, ServeConversion)
+	server.Handler = mux
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	klog.Infof("Serving conversion webhook on %s", server.Addr)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		klog.Fatalf("Could not serve conversion webhook : %s", err)
+	}
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	tests := map[string]interface{}{
+		"project member": &v2.ProjectMember{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v2.SchemeGroupVersion.String(), Kind: "ProjectMember"},
+			ObjectMeta: metav1.ObjectMeta{Name: "jdoe", Namespace: "team-dev"},
+			Spec: v2.ProjectMemberSpec{
+				UID: "jdoe", Username: "jdoe", Role: "admin",
+				DisplayName: "John Doe",
+				Attributes:  map[string]string{"employeeNumber": "42"},
+			},
+		},
+		"cluster member": &v2.ClusterMember{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v2.SchemeGroupVersion.String(), Kind: "ClusterMember"},
+			ObjectMeta: metav1.ObjectMeta{Name: "jdoe"},
+			Spec: v2.ClusterMemberSpec{
+				UID: "jdoe", Username: "jdoe", Role: "cluster-admin",
+				DisplayName: "John Doe",
+				Roles:       []v2.ClusterMemberRole{{Name: "cluster-admin", GroupDN: "cn=ops,ou=groups,dc=example,dc=org"}},
+			},
+		},
+	}
+
+	for name, object := range tests {
+		t.Run(name, func(t *testing.T) {
+			raw, err := json.Marshal(object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			old, err := convert(raw, v1.SchemeGroupVersion.String())
+			if err != nil {
+				t.Fatalf("could not convert to v1 : %s", err)
+			}
+			typeMeta := metav1.TypeMeta{}
+			json.Unmarshal(old, &typeMeta)
+			if typeMeta.APIVersion != v1.SchemeGroupVersion.String() {
+				t.Errorf("converted to %s instead of v1", typeMeta.APIVersion)
+			}
+
+			back, err := convert(old, v2.SchemeGroupVersion.String())
+			if err != nil {
+				t.Fatalf("could not convert back to v2 : %s", err)
+			}
+			var want, got map[string]interface{}
+			json.Unmarshal(raw, &want)
+			json.Unmarshal(back, &got)
+			if !equality.Semantic.DeepEqual(want, got) {
+				t.Errorf("v2 -> v1 -> v2 changed the object:\nwant %s\ngot  %s", raw, back)
+			}
+		})
+	}
+}
+
+func TestConvertUnsupported(t *testing.T) {
+	raw := []byte(`{"apiVersion":"cagip.github.com/v2","kind":"Project"}`)
+	if _, err := convert(raw, v1.SchemeGroupVersion.String()); err == nil {
+		t.Error("converted an unsupported kind")
+	}
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	membersclientset "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// CRDs are the definitions whose objects are converted by the webhook
+var CRDs = []string{"projectmembers.cagip.github.com", "clustermembers.cagip.github.com"}
+
+// MigrateStorage rewrites every ProjectMember and ClusterMember so that they
+// are stored as v2, then records v2 as the only stored version of the CRDs.
+// The conversion webhook must be served while it runs.
+func MigrateStorage(ctx context.Context, membersClient membersclientset.Interface, crdClient apiextensionsclientset.Interface) error {
+	projectMembers, err := membersClient.CagipV2().ProjectMembers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("could not list project members : %w", err)
+	}
+	for i := range projectMembers.Items {
+		member := &projectMembers.Items[i]
+		_, err := membersClient.CagipV2().ProjectMembers(member.Namespace).Update(ctx, member, metav1.UpdateOptions{})
+		if err := ignoreRewritten(err); err != nil {
+			return fmt.Errorf("could not rewrite project member %s/%s : %w", member.Namespace, member.Name, err)
+		}
+	}
+
+	clusterMembers, err := membersClient.CagipV2().ClusterMembers().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("could not list cluster members : %w", err)
+	}
+	for i := range clusterMembers.Items {
+		member := &clusterMembers.Items[i]
+		_, err := membersClient.CagipV2().ClusterMembers().Update(ctx, member, metav1.UpdateOptions{})
+		if err := ignoreRewritten(err); err != nil {
+			return fmt.Errorf("could not rewrite cluster member %s : %w", member.Name, err)
+		}
+	}
+	klog.Infof("Rewrote %d project members and %d cluster members as %s", len(projectMembers.Items), len(clusterMembers.Items), v2.SchemeGroupVersion)
+
+	for _, name := range CRDs {
+		crd, err := crdClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not get CustomResourceDefinition %s : %w", name, err)
+		}
+		crd.Status.StoredVersions = []string{v2.SchemeGroupVersion.Version}
+		if _, err := crdClient.ApiextensionsV1().CustomResourceDefinitions().UpdateStatus(ctx, crd, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("could not update stored versions of %s : %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ignoreRewritten ignores the objects deleted or written since they were
+// listed, the API server having stored them as v2 already
+func ignoreRewritten(err error) error {
+	if errors.IsNotFound(err) || errors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
@@ -1,42 +1,131 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/ca-gip/kubi-members/internal/api"
 	"github.com/ca-gip/kubi-members/internal/controller"
+	"github.com/ca-gip/kubi-members/internal/export"
 	"github.com/ca-gip/kubi-members/internal/ldap"
+	"github.com/ca-gip/kubi-members/internal/metrics"
+	"github.com/ca-gip/kubi-members/internal/utils"
+	"github.com/ca-gip/kubi-members/internal/webhook"
 	membersclientset "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned"
+	membersinformers "github.com/ca-gip/kubi-members/pkg/generated/informers/externalversions"
 	projectclientset "github.com/ca-gip/kubi/pkg/generated/clientset/versioned"
+	projectinformers "github.com/ca-gip/kubi/pkg/generated/informers/externalversions"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 )
 
 var (
-	masterURL  string
-	kubeconfig string
+	configFile   string
+	reloadPeriod time.Duration
+	masterURL    string
+	kubeconfig   string
+	once         bool
+	workers      int
+	options      controller.Options
+	webhookAddr  string
+	webhookCert  string
+	webhookKey   string
+	generateRBAC bool
+	apiAddr      string
+	metricsAddr  string
+	pushgateway  string
+	dryRun       bool
+	planJSON     string
+
+	leaderElect        bool
+	leaseName          string
+	leaseNamespace     string
+	leaseDuration      time.Duration
+	leaseRenewDeadline time.Duration
+	leaseRetryPeriod   time.Duration
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		runExport(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "webhook" {
+		runWebhook(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate-storage" {
+		runMigrateStorage(os.Args[2:])
+		return
+	}
+
+	flag.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "Path to a YAML or JSON configuration file, the LDAP_* and other environment variables override its values.")
+	flag.DurationVar(&reloadPeriod, "config-reload-interval", 30*time.Second, "Interval between two checks of the configuration file and of LDAP_PASSWD_FILE, changes are applied without restarting. 0 disables the reload.")
 	flag.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfig(), "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	flag.BoolVar(&once, "once", false, "Compute and write members a single time then exit, for use in a CronJob.")
+	flag.DurationVar(&options.ResyncInterval, "resync-interval", 10*time.Minute, "Interval between two LDAP polls of every project and cluster role.")
+	flag.IntVar(&options.MaxDeletionPercent, "max-deletion-percent", 50, "Refuse to delete more than this percentage of the members of a project, or of the cluster, in a single sync.")
+	flag.IntVar(&options.DeletionGuardMin, "deletion-guard-min", 5, "Number of deletions in a single sync below which --max-deletion-percent is not enforced.")
+	flag.BoolVar(&options.ForceDeletions, "force-deletions", false, "Apply the deletions refused by --max-deletion-percent, for a single --once run after a legitimate large removal.")
+	flag.IntVar(&workers, "workers", 2, "Number of projects reconciled concurrently.")
+
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the changes a sync would apply without writing anything, exit with code 2 when members drifted from LDAP. Same as the plan subcommand.")
+	flag.StringVar(&planJSON, "plan-json", "", "File the plan is written to as JSON with --dry-run, - for standard output instead of the human readable plan.")
+	flag.StringVar(&apiAddr, "api-bind-address", "", "The address the read only members API listens on, disabled when empty. It has no authentication and exposes the DN and mail of every member.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8000", "The address /metrics listens on, disabled when empty. It may be the address of the API.")
+	flag.StringVar(&pushgateway, "pushgateway-url", "", "Pushgateway the metrics are pushed to once members are written, with --once.")
+	flag.BoolVar(&generateRBAC, "generate-rbac", false, "Generate RoleBindings from project members and ClusterRoleBindings from cluster members, see RBAC_* variables.")
+	flag.StringVar(&webhookAddr, "webhook-bind-address", ":8443", "The address the v1/v2 conversion webhook listens on.")
+	flag.StringVar(&webhookCert, "webhook-cert-file", "", "TLS certificate of the conversion webhook. The webhook is disabled when empty, and never served with --once: use the webhook subcommand.")
+	flag.StringVar(&webhookKey, "webhook-key-file", "", "TLS private key of the conversion webhook.")
+
+	flag.BoolVar(&leaderElect, "leader-elect", false, "Elect a leader through a Lease before reconciling members, for deployments with several replicas. Every replica serves the HTTP API and the webhook.")
+	flag.StringVar(&leaseName, "leader-elect-lease-name", "kubi-members", "Name of the Lease used for leader election.")
+	flag.StringVar(&leaseNamespace, "leader-elect-lease-namespace", getenv("POD_NAMESPACE", "kube-system"), "Namespace of the Lease used for leader election.")
+	flag.DurationVar(&leaseDuration, "leader-elect-lease-duration", 15*time.Second, "Duration non-leaders wait before trying to acquire a Lease that was not renewed.")
+	flag.DurationVar(&leaseRenewDeadline, "leader-elect-renew-deadline", 10*time.Second, "Duration the leader retries renewing the Lease before giving up leadership.")
+	flag.DurationVar(&leaseRetryPeriod, "leader-elect-retry-period", 2*time.Second, "Duration between two attempts to acquire or renew the Lease.")
 
 	klog.InitFlags(nil)
 
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "plan" {
+		dryRun = true
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+	if dryRun {
+		once = true
+	}
 
-	// Load kube config
-	cfg, err := rest.InClusterConfig()
+	config, err := utils.LoadConfig(configFile)
 	if err != nil {
-		cfg, err = clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
-		if err != nil {
-			klog.Fatalf("Error building kubeconfig: %s", err.Error())
-		}
+		klog.Fatalf("Invalid configuration: %s", err.Error())
 	}
 
+	cfg := buildConfig()
+
 	// Generate clientsets
 	configMapClient, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
@@ -55,13 +144,355 @@ func main() {
 
 	klog.Info("Creating LDAP client")
 
-	ldapClient := ldap.NewLdap()
+	ldapClient := ldap.NewLdap(config)
+
+	stopCh := utils.SetupSignalHandler()
+
+	if webhookCert != "" && !once {
+		go webhook.Serve(webhookAddr, webhookCert, webhookKey, stopCh)
+	}
+
+	projectInformerFactory := projectinformers.NewSharedInformerFactory(projectClient, 0)
+	membersInformerFactory := membersinformers.NewSharedInformerFactory(membersClient, 0)
+	// Only the bindings generated by kubi-members are watched
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(configMapClient, 0,
+		kubeinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = utils.ManagedByLabel + "=" + utils.ManagedBy
+		}))
+
+	if generateRBAC {
+		klog.InfoS("Generating RBAC bindings", "SubjectAttribute", config.RBAC.SubjectAttribute,
+			"ProjectRoles", config.RBAC.ProjectRoles, "ClusterRoles", config.RBAC.ClusterRoles)
+		if len(config.RBAC.ProjectRoles) == 0 && len(config.RBAC.ClusterRoles) == 0 {
+			klog.Warning("No role is mapped to a ClusterRole, no binding will be generated, see RBAC_PROJECT_ROLES and RBAC_CLUSTER_ROLES")
+		}
+		rbacConfig := config.RBAC
+		options.RBAC = &rbacConfig
+	}
+
+	controller := controller.NewController(configMapClient, projectClient, membersClient,
+		projectInformerFactory.Cagip().V1().Projects(),
+		membersInformerFactory.Cagip().V2().ClusterMembers(),
+		membersInformerFactory.Cagip().V2().ProjectMembers(),
+		kubeInformerFactory.Rbac().V1().RoleBindings(),
+		kubeInformerFactory.Rbac().V1().ClusterRoleBindings(),
+		ldapClient, options)
+
+	if metricsAddr != "" && !once {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		if apiAddr == metricsAddr {
+			server := api.NewServer(membersInformerFactory.Cagip().V2().ClusterMembers(), membersInformerFactory.Cagip().V2().ProjectMembers())
+			mux.Handle("/", server.Handler())
+		}
+		go api.Serve(metricsAddr, mux, stopCh)
+	}
+	if apiAddr != "" && apiAddr != metricsAddr && !once {
+		server := api.NewServer(membersInformerFactory.Cagip().V2().ClusterMembers(), membersInformerFactory.Cagip().V2().ProjectMembers())
+		go api.Serve(apiAddr, server.Handler(), stopCh)
+	}
+
+	if reloadPeriod > 0 && !once && (configFile != "" || config.LDAP.BindPasswordFile != "") {
+		go utils.WatchConfig(configFile, config, reloadPeriod, stopCh, controller.Reload)
+	}
+
+	projectInformerFactory.Start(stopCh)
+	membersInformerFactory.Start(stopCh)
+	kubeInformerFactory.Start(stopCh)
+
+	if dryRun {
+		if err := controller.WaitForCacheSync(stopCh); err != nil {
+			klog.Fatalf("Error running controller: %s", err.Error())
+		}
+		os.Exit(runPlan(controller))
+	}
+
+	if once {
+		if err := controller.WaitForCacheSync(stopCh); err != nil {
+			klog.Fatalf("Error running controller: %s", err.Error())
+		}
+		err := controller.RunOnce()
+		controller.ShutdownEvents()
+		if pushgateway != "" {
+			if err := metrics.Push(pushgateway, utils.ManagedBy); err != nil {
+				klog.Errorf("Could not push metrics to %s : %s", pushgateway, err)
+			}
+		}
+		if err != nil {
+			klog.Fatalf("Error running controller: %s", err.Error())
+		}
+		return
+	}
+
+	if !leaderElect {
+		if err := controller.Run(workers, stopCh); err != nil {
+			klog.Fatalf("Error running controller: %s", err.Error())
+		}
+		return
+	}
+
+	runLeaderElection(configMapClient, stopCh, func(ctx context.Context) {
+		if err := controller.Run(workers, ctx.Done()); err != nil {
+			klog.Fatalf("Error running controller: %s", err.Error())
+		}
+	})
+}
+
+// runPlan prints the changes a sync would apply and returns the exit code:
+// 2 when members drifted from LDAP, 1 when a lookup failed, 0 otherwise
+func runPlan(c *controller.Controller) int {
+	plan, err := c.Plan()
+	if err != nil {
+		klog.Errorf("Error computing plan: %s", err.Error())
+		return 1
+	}
+
+	if planJSON != "-" {
+		plan.WriteText(os.Stdout)
+	}
+	if planJSON != "" {
+		out := os.Stdout
+		if planJSON != "-" {
+			if out, err = os.Create(planJSON); err != nil {
+				klog.Errorf("Could not create %s : %s", planJSON, err)
+				return 1
+			}
+			defer out.Close()
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil {
+			klog.Errorf("Could not write plan : %s", err)
+			return 1
+		}
+	}
+
+	if plan.HasDrift() {
+		return 2
+	}
+	if len(plan.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runLeaderElection calls run once this replica holds the Lease, and exits
+// when leadership is lost so that the replica restarts as a follower
+func runLeaderElection(kubeClient kubernetes.Interface, stopCh <-chan struct{}, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		klog.Fatalf("Error getting hostname: %s", err.Error())
+	}
+	identity := hostname + "_" + string(uuid.NewUUID())
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: leaseNamespace},
+		Client:     kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	klog.Infof("Waiting for leadership of Lease %s/%s as %s", leaseNamespace, leaseName, identity)
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   leaseRenewDeadline,
+		RetryPeriod:     leaseRetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					klog.Info("Released leadership")
+					return
+				}
+				klog.Fatalf("Lost leadership of Lease %s/%s", leaseNamespace, leaseName)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					klog.Infof("Current leader is %s", leader)
+				}
+			},
+		},
+	})
+}
+
+func getenv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// runConfig runs the config subcommands, only validate for now: it checks a
+// configuration file without contacting LDAP nor the cluster, and prints the
+// effective configuration, environment included, with its secrets redacted
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: kubi-members config validate [--config FILE | FILE]")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	fs.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "Path to the YAML or JSON configuration file to validate.")
+	klog.InitFlags(fs)
+	fs.Parse(args[1:])
+	if fs.NArg() > 0 {
+		configFile = fs.Arg(0)
+	}
+
+	config, err := utils.LoadConfig(configFile)
+	if out, err := yaml.Marshal(config.Redacted()); err == nil {
+		os.Stdout.Write(out)
+	}
+	if err != nil {
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			for _, err := range agg.Errors() {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// runWebhook only serves the conversion webhook, for deployments running the
+// controller with --once from a CronJob. Without certificate files, the
+// certificate is generated in a Secret and its CA set in the CRDs.
+func runWebhook(args []string) {
+	var secretName, service, namespace string
+	fs := flag.NewFlagSet("webhook", flag.ExitOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfig(), "Path to a kubeconfig. Only required if out-of-cluster.")
+	fs.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	fs.StringVar(&webhookAddr, "webhook-bind-address", ":8443", "The address the v1/v2 conversion webhook listens on.")
+	fs.StringVar(&webhookCert, "webhook-cert-file", "", "TLS certificate of the conversion webhook, generated in --webhook-secret when empty.")
+	fs.StringVar(&webhookKey, "webhook-key-file", "", "TLS private key of the conversion webhook.")
+	fs.StringVar(&secretName, "webhook-secret", "kubi-members-webhook-tls", "Secret holding the generated certificate of the webhook and its CA.")
+	fs.StringVar(&service, "webhook-service", "kubi-members", "Service of the webhook, named by the generated certificate.")
+	fs.StringVar(&namespace, "webhook-namespace", getenv("POD_NAMESPACE", "kube-system"), "Namespace of the Service and of the Secret of the webhook.")
+	klog.InitFlags(fs)
+	fs.Parse(args)
+
+	stopCh := utils.SetupSignalHandler()
+	if webhookCert != "" {
+		webhook.Serve(webhookAddr, webhookCert, webhookKey, stopCh)
+		return
+	}
+
+	cfg := buildConfig()
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes kubeClient: %s", err.Error())
+	}
+	crdClient, err := apiextensionsclientset.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes crdClient: %s", err.Error())
+	}
+	cert, err := webhook.EnsureCertificate(context.Background(), kubeClient, crdClient, namespace, secretName, service)
+	if err != nil {
+		klog.Fatalf("Could not set up webhook certificate : %s", err)
+	}
+	webhook.ServeCertificate(webhookAddr, cert, stopCh)
+}
+
+// runMigrateStorage rewrites the ProjectMembers and ClusterMembers stored as
+// v1 as v2, the conversion webhook being served
+func runMigrateStorage(args []string) {
+	fs := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfig(), "Path to a kubeconfig. Only required if out-of-cluster.")
+	fs.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	klog.InitFlags(fs)
+	fs.Parse(args)
+
+	cfg := buildConfig()
+	membersClient, err := membersclientset.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes membersClient: %s", err.Error())
+	}
+	crdClient, err := apiextensionsclientset.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes crdClient: %s", err.Error())
+	}
+	if err := webhook.MigrateStorage(context.Background(), membersClient, crdClient); err != nil {
+		klog.Fatalf("Could not migrate storage : %s", err)
+	}
+}
+
+// runExport prints the access matrix computed from the ClusterMembers and
+// ProjectMembers of the cluster
+func runExport(args []string) {
+	var format, output string
+	var filter export.Filter
 
-	controller := controller.NewController(configMapClient, projectClient, membersClient, ldapClient)
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfig(), "Path to a kubeconfig. Only required if out-of-cluster.")
+	fs.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	fs.StringVar(&format, "format", "csv", "Output format: "+strings.Join(export.Formats, ", ")+".")
+	fs.StringVar(&output, "output", "", "File the matrix is written to, standard output when empty.")
+	fs.StringVar(&filter.Namespace, "namespace", "", "Only export the members of this namespace, "+export.ClusterColumn+" for cluster roles.")
+	fs.StringVar(&filter.Role, "role", "", "Only export the access granted by this role.")
+	fs.StringVar(&filter.User, "user", "", "Only export the access of the user with this uid, username or mail.")
+	klog.InitFlags(fs)
+	fs.Parse(args)
 
-	if err := controller.Run(); err != nil {
-		klog.Fatalf("Error running controller: %s", err.Error())
+	membersClient, err := membersclientset.NewForConfig(buildConfig())
+	if err != nil {
+		klog.Fatalf("Error building kubernetes membersClient: %s", err.Error())
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	membersInformerFactory := membersinformers.NewSharedInformerFactory(membersClient, 0)
+	clusterMembers := membersInformerFactory.Cagip().V2().ClusterMembers()
+	projectMembers := membersInformerFactory.Cagip().V2().ProjectMembers()
+	clusterMembers.Informer()
+	projectMembers.Informer()
+	membersInformerFactory.Start(stopCh)
+	for informer, synced := range membersInformerFactory.WaitForCacheSync(stopCh) {
+		if !synced {
+			klog.Fatalf("Error syncing %s cache", informer)
+		}
+	}
+
+	clusterMemberList, err := clusterMembers.Lister().List(utils.DefaultLabelSelector())
+	if err != nil {
+		klog.Fatalf(utils.CouldNotList, "cluster members")
+	}
+	projectMemberList, err := projectMembers.Lister().List(utils.DefaultLabelSelector())
+	if err != nil {
+		klog.Fatalf(utils.CouldNotList, "project members")
+	}
+	matrix := export.Build(clusterMemberList, projectMemberList, filter)
+
+	out := os.Stdout
+	if output != "" {
+		if out, err = os.Create(output); err != nil {
+			klog.Fatalf("Could not create %s : %s", output, err)
+		}
+		defer out.Close()
+	}
+	if err := export.Write(out, matrix, format); err != nil {
+		klog.Fatalf("Could not export access matrix : %s", err)
+	}
+}
+
+// buildConfig loads the in-cluster config, or the kubeconfig out of cluster
+func buildConfig() *rest.Config {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		cfg, err = clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
+		if err != nil {
+			klog.Fatalf("Error building kubeconfig: %s", err.Error())
+		}
 	}
+	return cfg
 }
 
 func defaultKubeconfig() string {
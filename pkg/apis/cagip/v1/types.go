@@ -10,10 +10,14 @@ type ProjectMember struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	UID					string `json:"uid,omitempty"`
-	Dn      			string `json:"dn,omitempty"`
-	Username 			string `json:"username,omitempty"`
-	Mail     			string `json:"mail,omitempty"`
+	UID      string `json:"uid,omitempty"`
+	Dn       string `json:"dn,omitempty"`
+	Username string `json:"username,omitempty"`
+	Mail     string `json:"mail,omitempty"`
+	// Role is the project role with the highest priority granted to the member
+	Role string `json:"role,omitempty"`
+
+	Status MemberStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -30,11 +34,41 @@ type ClusterMember struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	UID					string `json:"uid,omitempty"`
-	Dn       			string `json:"dn,omitempty"`
-	Username 			string `json:"username,omitempty"`
-	Mail     			string `json:"mail,omitempty"`
-	Role     			string `json:"role,omitempty"`
+	UID      string `json:"uid,omitempty"`
+	Dn       string `json:"dn,omitempty"`
+	Username string `json:"username,omitempty"`
+	Mail     string `json:"mail,omitempty"`
+	Role     string `json:"role,omitempty"`
+	// Roles lists every role granted to the member and the group granting it,
+	// Role being the one with the highest priority
+	Roles []ClusterMemberRole `json:"roles,omitempty"`
+
+	Status MemberStatus `json:"status,omitempty"`
+}
+
+// ClusterMemberRole is a cluster role granted through membership of an LDAP group
+type ClusterMemberRole struct {
+	Name    string `json:"name"`
+	GroupDN string `json:"groupDN"`
+}
+
+const (
+	// ConditionSynced is true when the membership was confirmed by the last LDAP lookup
+	ConditionSynced = "Synced"
+	// ConditionSourceUnavailable is true when the last LDAP lookup of the source
+	// groups failed and the member was kept as is
+	ConditionSourceUnavailable = "SourceUnavailable"
+)
+
+// MemberStatus records when and from where a membership was last confirmed
+type MemberStatus struct {
+	// LastSyncedTime is the last time the membership was confirmed against LDAP
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+	// ObservedSourceDN is the source DN of the owning Project at that time
+	ObservedSourceDN string `json:"observedSourceDN,omitempty"`
+	// SourceGroups are the LDAP groups granting the membership
+	SourceGroups []string           `json:"sourceGroups,omitempty"`
+	Conditions   []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient:nonNamespaced
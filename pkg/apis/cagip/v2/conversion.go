@@ -0,0 +1,154 @@
+package v2
+
+import (
+	"encoding/json"
+
+	v1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FieldsAnnotation holds the v2 fields without v1 equivalent of objects served
+// as v1, so that they are restored when converted back to v2
+const FieldsAnnotation = "cagip.github.com/v2-fields"
+
+// fields are the v2 spec fields kept in FieldsAnnotation
+type fields struct {
+	DisplayName string            `json:"displayName,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+// ProjectMemberFromV1 moves the top level fields of a v1 ProjectMember under spec
+func ProjectMemberFromV1(in *v1.ProjectMember) *ProjectMember {
+	meta := *in.ObjectMeta.DeepCopy()
+	extra := popFields(&meta)
+	return &ProjectMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: in.Kind},
+		ObjectMeta: meta,
+		Spec: ProjectMemberSpec{
+			UID:         in.UID,
+			Dn:          in.Dn,
+			Username:    in.Username,
+			Mail:        in.Mail,
+			Role:        in.Role,
+			DisplayName: extra.DisplayName,
+			Attributes:  extra.Attributes,
+		},
+		Status: statusFromV1(in.Status),
+	}
+}
+
+// ProjectMemberToV1 moves the spec of a v2 ProjectMember back to the top level,
+// DisplayName and Attributes being kept in FieldsAnnotation
+func ProjectMemberToV1(in *ProjectMember) *v1.ProjectMember {
+	meta := *in.ObjectMeta.DeepCopy()
+	pushFields(&meta, fields{DisplayName: in.Spec.DisplayName, Attributes: in.Spec.Attributes})
+	return &v1.ProjectMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1.SchemeGroupVersion.String(), Kind: in.Kind},
+		ObjectMeta: meta,
+		UID:        in.Spec.UID,
+		Dn:         in.Spec.Dn,
+		Username:   in.Spec.Username,
+		Mail:       in.Spec.Mail,
+		Role:       in.Spec.Role,
+		Status:     statusToV1(in.Status),
+	}
+}
+
+// ClusterMemberFromV1 moves the top level fields of a v1 ClusterMember under spec
+func ClusterMemberFromV1(in *v1.ClusterMember) *ClusterMember {
+	meta := *in.ObjectMeta.DeepCopy()
+	extra := popFields(&meta)
+	out := &ClusterMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: in.Kind},
+		ObjectMeta: meta,
+		Spec: ClusterMemberSpec{
+			UID:         in.UID,
+			Dn:          in.Dn,
+			Username:    in.Username,
+			Mail:        in.Mail,
+			Role:        in.Role,
+			DisplayName: extra.DisplayName,
+			Attributes:  extra.Attributes,
+		},
+		Status: statusFromV1(in.Status),
+	}
+	for _, role := range in.Roles {
+		out.Spec.Roles = append(out.Spec.Roles, ClusterMemberRole{Name: role.Name, GroupDN: role.GroupDN})
+	}
+	return out
+}
+
+// ClusterMemberToV1 moves the spec of a v2 ClusterMember back to the top level,
+// DisplayName and Attributes being kept in FieldsAnnotation
+func ClusterMemberToV1(in *ClusterMember) *v1.ClusterMember {
+	meta := *in.ObjectMeta.DeepCopy()
+	pushFields(&meta, fields{DisplayName: in.Spec.DisplayName, Attributes: in.Spec.Attributes})
+	out := &v1.ClusterMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1.SchemeGroupVersion.String(), Kind: in.Kind},
+		ObjectMeta: meta,
+		UID:        in.Spec.UID,
+		Dn:         in.Spec.Dn,
+		Username:   in.Spec.Username,
+		Mail:       in.Spec.Mail,
+		Role:       in.Spec.Role,
+		Status:     statusToV1(in.Status),
+	}
+	for _, role := range in.Spec.Roles {
+		out.Roles = append(out.Roles, v1.ClusterMemberRole{Name: role.Name, GroupDN: role.GroupDN})
+	}
+	return out
+}
+
+// pushFields stores extra in FieldsAnnotation, unless it is empty
+func pushFields(meta *metav1.ObjectMeta, extra fields) {
+	delete(meta.Annotations, FieldsAnnotation)
+	if extra.DisplayName == "" && len(extra.Attributes) == 0 {
+		return
+	}
+	data, err := json.Marshal(extra)
+	if err != nil {
+		return
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[FieldsAnnotation] = string(data)
+}
+
+// popFields removes FieldsAnnotation and returns the fields it held, an
+// unreadable annotation being dropped
+func popFields(meta *metav1.ObjectMeta) fields {
+	extra := fields{}
+	data, found := meta.Annotations[FieldsAnnotation]
+	if !found {
+		return extra
+	}
+	delete(meta.Annotations, FieldsAnnotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	if err := json.Unmarshal([]byte(data), &extra); err != nil {
+		return fields{}
+	}
+	return extra
+}
+
+func statusFromV1(in v1.MemberStatus) MemberStatus {
+	status := in.DeepCopy()
+	return MemberStatus{
+		LastSyncedTime:   status.LastSyncedTime,
+		ObservedSourceDN: status.ObservedSourceDN,
+		SourceGroups:     status.SourceGroups,
+		Conditions:       status.Conditions,
+	}
+}
+
+func statusToV1(in MemberStatus) v1.MemberStatus {
+	status := in.DeepCopy()
+	return v1.MemberStatus{
+		LastSyncedTime:   status.LastSyncedTime,
+		ObservedSourceDN: status.ObservedSourceDN,
+		SourceGroups:     status.SourceGroups,
+		Conditions:       status.Conditions,
+	}
+}
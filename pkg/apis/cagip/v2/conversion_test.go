@@ -0,0 +1,103 @@
+package v2
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var synced = metav1.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestProjectMemberRoundTrip(t *testing.T) {
+	tests := map[string]*ProjectMember{
+		"mapped attributes": {
+			TypeMeta:   metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "ProjectMember"},
+			ObjectMeta: metav1.ObjectMeta{Name: "jdoe", Namespace: "team-dev", Labels: map[string]string{"creator": "kubi-members"}},
+			Spec: ProjectMemberSpec{
+				UID: "jdoe", Dn: "cn=jdoe,ou=people,dc=example,dc=org", Username: "jdoe", Mail: "jdoe@example.org", Role: "admin",
+				DisplayName: "John Doe",
+				Attributes:  map[string]string{"employeeNumber": "42"},
+			},
+			Status: MemberStatus{LastSyncedTime: &synced, SourceGroups: []string{"cn=dev,ou=groups,dc=example,dc=org"}},
+		},
+		"no mapped attributes": {
+			TypeMeta:   metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "ProjectMember"},
+			ObjectMeta: metav1.ObjectMeta{Name: "jdoe", Namespace: "team-dev", Annotations: map[string]string{"note": "kept"}},
+			Spec:       ProjectMemberSpec{UID: "jdoe", Username: "jdoe", Role: "view"},
+		},
+	}
+
+	for name, member := range tests {
+		t.Run(name, func(t *testing.T) {
+			converted := ProjectMemberFromV1(ProjectMemberToV1(member))
+			if !equality.Semantic.DeepEqual(member, converted) {
+				t.Errorf("v2 -> v1 -> v2 changed the member:\nwant %+v\ngot  %+v", member, converted)
+			}
+		})
+	}
+}
+
+func TestClusterMemberRoundTrip(t *testing.T) {
+	member := &ClusterMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "ClusterMember"},
+		ObjectMeta: metav1.ObjectMeta{Name: "jdoe", Labels: map[string]string{"creator": "kubi-members"}},
+		Spec: ClusterMemberSpec{
+			UID: "jdoe", Username: "jdoe", Role: "cluster-admin",
+			DisplayName: "John Doe",
+			Attributes:  map[string]string{"department": "ops"},
+			Roles:       []ClusterMemberRole{{Name: "cluster-admin", GroupDN: "cn=ops,ou=groups,dc=example,dc=org"}},
+		},
+		Status: MemberStatus{LastSyncedTime: &synced},
+	}
+
+	converted := ClusterMemberFromV1(ClusterMemberToV1(member))
+	if !equality.Semantic.DeepEqual(member, converted) {
+		t.Errorf("v2 -> v1 -> v2 changed the member:\nwant %+v\ngot  %+v", member, converted)
+	}
+}
+
+func TestV1RoundTrip(t *testing.T) {
+	project := &v1.ProjectMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1.SchemeGroupVersion.String(), Kind: "ProjectMember"},
+		ObjectMeta: metav1.ObjectMeta{Name: "jdoe", Namespace: "team-dev"},
+		UID:        "jdoe", Username: "jdoe", Mail: "jdoe@example.org", Role: "admin",
+	}
+	if converted := ProjectMemberToV1(ProjectMemberFromV1(project)); !equality.Semantic.DeepEqual(project, converted) {
+		t.Errorf("v1 -> v2 -> v1 changed the project member:\nwant %+v\ngot  %+v", project, converted)
+	}
+
+	cluster := &v1.ClusterMember{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1.SchemeGroupVersion.String(), Kind: "ClusterMember"},
+		ObjectMeta: metav1.ObjectMeta{Name: "jdoe"},
+		UID:        "jdoe", Username: "jdoe", Role: "cluster-admin",
+		Roles: []v1.ClusterMemberRole{{Name: "cluster-admin", GroupDN: "cn=ops,ou=groups,dc=example,dc=org"}},
+	}
+	if converted := ClusterMemberToV1(ClusterMemberFromV1(cluster)); !equality.Semantic.DeepEqual(cluster, converted) {
+		t.Errorf("v1 -> v2 -> v1 changed the cluster member:\nwant %+v\ngot  %+v", cluster, converted)
+	}
+}
+
+func TestV1KeepsV2Fields(t *testing.T) {
+	member := &ProjectMember{
+		ObjectMeta: metav1.ObjectMeta{Name: "jdoe"},
+		Spec:       ProjectMemberSpec{UID: "jdoe", DisplayName: "John Doe"},
+	}
+	out := ProjectMemberToV1(member)
+	if out.Annotations[FieldsAnnotation] != `{"displayName":"John Doe"}` {
+		t.Errorf("unexpected %s annotation %q", FieldsAnnotation, out.Annotations[FieldsAnnotation])
+	}
+	if member.Annotations != nil {
+		t.Errorf("conversion modified its input: %v", member.Annotations)
+	}
+}
+
+func TestInvalidFieldsAnnotation(t *testing.T) {
+	in := &v1.ProjectMember{ObjectMeta: metav1.ObjectMeta{Name: "jdoe", Annotations: map[string]string{FieldsAnnotation: "{"}}}
+	out := ProjectMemberFromV1(in)
+	if out.Spec.DisplayName != "" || out.Spec.Attributes != nil || out.Annotations != nil {
+		t.Errorf("invalid annotation not dropped: %+v", out)
+	}
+}
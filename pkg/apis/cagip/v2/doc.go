@@ -0,0 +1,3 @@
+// +k8s:deepcopy-gen=package
+// +groupName=cagip.github.com
+package v2
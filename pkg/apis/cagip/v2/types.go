@@ -0,0 +1,96 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ProjectMember struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProjectMemberSpec `json:"spec,omitempty"`
+	Status MemberStatus      `json:"status,omitempty"`
+}
+
+// ProjectMemberSpec identifies a user entitled to a namespace and its role
+type ProjectMemberSpec struct {
+	UID      string `json:"uid,omitempty"`
+	Dn       string `json:"dn,omitempty"`
+	Username string `json:"username,omitempty"`
+	Mail     string `json:"mail,omitempty"`
+	// DisplayName and Attributes are read from LDAP through the attribute mapping
+	DisplayName string            `json:"displayName,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	// Role is the project role with the highest priority granted to the member
+	Role string `json:"role,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ProjectMemberList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProjectMember `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterMember struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterMemberSpec `json:"spec,omitempty"`
+	Status MemberStatus      `json:"status,omitempty"`
+}
+
+// ClusterMemberSpec identifies a user entitled to the cluster and its roles
+type ClusterMemberSpec struct {
+	UID      string `json:"uid,omitempty"`
+	Dn       string `json:"dn,omitempty"`
+	Username string `json:"username,omitempty"`
+	Mail     string `json:"mail,omitempty"`
+	// DisplayName and Attributes are read from LDAP through the attribute mapping
+	DisplayName string            `json:"displayName,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Role        string            `json:"role,omitempty"`
+	// Roles lists every role granted to the member and the group granting it,
+	// Role being the one with the highest priority
+	Roles []ClusterMemberRole `json:"roles,omitempty"`
+}
+
+// ClusterMemberRole is a cluster role granted through membership of an LDAP group
+type ClusterMemberRole struct {
+	Name    string `json:"name"`
+	GroupDN string `json:"groupDN"`
+}
+
+const (
+	// ConditionSynced is true when the membership was confirmed by the last LDAP lookup
+	ConditionSynced = "Synced"
+	// ConditionSourceUnavailable is true when the last LDAP lookup of the source
+	// groups failed and the member was kept as is
+	ConditionSourceUnavailable = "SourceUnavailable"
+)
+
+// MemberStatus records when and from where a membership was last confirmed
+type MemberStatus struct {
+	// LastSyncedTime is the last time the membership was confirmed against LDAP,
+	// refreshed hourly while nothing else changes
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+	// ObservedSourceDN is the source DN of the owning Project at that time
+	ObservedSourceDN string `json:"observedSourceDN,omitempty"`
+	// SourceGroups are the LDAP groups granting the membership
+	SourceGroups []string           `json:"sourceGroups,omitempty"`
+	Conditions   []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterMemberList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterMember `json:"items"`
+}
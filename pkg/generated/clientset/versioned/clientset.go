@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	cagipv1 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v1"
+	cagipv2 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v2"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -15,6 +16,7 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	CagipV1() cagipv1.CagipV1Interface
+	CagipV2() cagipv2.CagipV2Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -22,6 +24,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	cagipV1 *cagipv1.CagipV1Client
+	cagipV2 *cagipv2.CagipV2Client
 }
 
 // CagipV1 retrieves the CagipV1Client
@@ -29,6 +32,11 @@ func (c *Clientset) CagipV1() cagipv1.CagipV1Interface {
 	return c.cagipV1
 }
 
+// CagipV2 retrieves the CagipV2Client
+func (c *Clientset) CagipV2() cagipv2.CagipV2Interface {
+	return c.cagipV2
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -77,6 +85,10 @@ func NewForConfigAndClient(c *rest.Config, httpClient *http.Client) (*Clientset,
 	if err != nil {
 		return nil, err
 	}
+	cs.cagipV2, err = cagipv2.NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
@@ -99,6 +111,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.cagipV1 = cagipv1.New(c)
+	cs.cagipV2 = cagipv2.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
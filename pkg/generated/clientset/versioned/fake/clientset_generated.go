@@ -6,6 +6,8 @@ import (
 	clientset "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned"
 	cagipv1 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v1"
 	fakecagipv1 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v1/fake"
+	cagipv2 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v2"
+	fakecagipv2 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v2/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -67,3 +69,8 @@ var (
 func (c *Clientset) CagipV1() cagipv1.CagipV1Interface {
 	return &fakecagipv1.FakeCagipV1{Fake: &c.Fake}
 }
+
+// CagipV2 retrieves the CagipV2Client
+func (c *Clientset) CagipV2() cagipv2.CagipV2Interface {
+	return &fakecagipv2.FakeCagipV2{Fake: &c.Fake}
+}
@@ -4,6 +4,7 @@ package fake
 
 import (
 	cagipv1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	cagipv2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -16,6 +17,7 @@ var codecs = serializer.NewCodecFactory(scheme)
 
 var localSchemeBuilder = runtime.SchemeBuilder{
 	cagipv1.AddToScheme,
+	cagipv2.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -4,6 +4,7 @@ package scheme
 
 import (
 	cagipv1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	cagipv2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -16,6 +17,7 @@ var Codecs = serializer.NewCodecFactory(Scheme)
 var ParameterCodec = runtime.NewParameterCodec(Scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	cagipv1.AddToScheme,
+	cagipv2.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -24,6 +24,7 @@ type ClusterMembersGetter interface {
 type ClusterMemberInterface interface {
 	Create(ctx context.Context, clusterMember *v1.ClusterMember, opts metav1.CreateOptions) (*v1.ClusterMember, error)
 	Update(ctx context.Context, clusterMember *v1.ClusterMember, opts metav1.UpdateOptions) (*v1.ClusterMember, error)
+	UpdateStatus(ctx context.Context, clusterMember *v1.ClusterMember, opts metav1.UpdateOptions) (*v1.ClusterMember, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.ClusterMember, error)
@@ -112,6 +113,21 @@ func (c *clusterMembers) Update(ctx context.Context, clusterMember *v1.ClusterMe
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *clusterMembers) UpdateStatus(ctx context.Context, clusterMember *v1.ClusterMember, opts metav1.UpdateOptions) (result *v1.ClusterMember, err error) {
+	result = &v1.ClusterMember{}
+	err = c.client.Put().
+		Resource("clustermembers").
+		Name(clusterMember.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(clusterMember).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the clusterMember and deletes it. Returns an error if one occurs.
 func (c *clusterMembers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
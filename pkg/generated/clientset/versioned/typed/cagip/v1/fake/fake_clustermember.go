@@ -80,6 +80,17 @@ func (c *FakeClusterMembers) Update(ctx context.Context, clusterMember *cagipv1.
 	return obj.(*cagipv1.ClusterMember), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeClusterMembers) UpdateStatus(ctx context.Context, clusterMember *cagipv1.ClusterMember, opts v1.UpdateOptions) (*cagipv1.ClusterMember, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(clustermembersResource, "status", clusterMember), &cagipv1.ClusterMember{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cagipv1.ClusterMember), err
+}
+
 // Delete takes name of the clusterMember and deletes it. Returns an error if one occurs.
 func (c *FakeClusterMembers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -86,6 +86,18 @@ func (c *FakeProjectMembers) Update(ctx context.Context, projectMember *cagipv1.
 	return obj.(*cagipv1.ProjectMember), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeProjectMembers) UpdateStatus(ctx context.Context, projectMember *cagipv1.ProjectMember, opts v1.UpdateOptions) (*cagipv1.ProjectMember, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(projectmembersResource, "status", c.ns, projectMember), &cagipv1.ProjectMember{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cagipv1.ProjectMember), err
+}
+
 // Delete takes name of the projectMember and deletes it. Returns an error if one occurs.
 func (c *FakeProjectMembers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
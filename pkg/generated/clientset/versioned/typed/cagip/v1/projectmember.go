@@ -24,6 +24,7 @@ type ProjectMembersGetter interface {
 type ProjectMemberInterface interface {
 	Create(ctx context.Context, projectMember *v1.ProjectMember, opts metav1.CreateOptions) (*v1.ProjectMember, error)
 	Update(ctx context.Context, projectMember *v1.ProjectMember, opts metav1.UpdateOptions) (*v1.ProjectMember, error)
+	UpdateStatus(ctx context.Context, projectMember *v1.ProjectMember, opts metav1.UpdateOptions) (*v1.ProjectMember, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.ProjectMember, error)
@@ -119,6 +120,22 @@ func (c *projectMembers) Update(ctx context.Context, projectMember *v1.ProjectMe
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *projectMembers) UpdateStatus(ctx context.Context, projectMember *v1.ProjectMember, opts metav1.UpdateOptions) (result *v1.ProjectMember, err error) {
+	result = &v1.ProjectMember{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		Name(projectMember.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(projectMember).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the projectMember and deletes it. Returns an error if one occurs.
 func (c *projectMembers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
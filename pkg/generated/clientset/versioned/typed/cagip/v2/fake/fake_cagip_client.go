@@ -0,0 +1,28 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v2 "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/typed/cagip/v2"
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+)
+
+type FakeCagipV2 struct {
+	*testing.Fake
+}
+
+func (c *FakeCagipV2) ClusterMembers() v2.ClusterMemberInterface {
+	return &FakeClusterMembers{c}
+}
+
+func (c *FakeCagipV2) ProjectMembers(namespace string) v2.ProjectMemberInterface {
+	return &FakeProjectMembers{c, namespace}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *FakeCagipV2) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
@@ -0,0 +1,179 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	"context"
+	"time"
+
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	scheme "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ProjectMembersGetter has a method to return a ProjectMemberInterface.
+// A group's client should implement this interface.
+type ProjectMembersGetter interface {
+	ProjectMembers(namespace string) ProjectMemberInterface
+}
+
+// ProjectMemberInterface has methods to work with ProjectMember resources.
+type ProjectMemberInterface interface {
+	Create(ctx context.Context, projectMember *v2.ProjectMember, opts v1.CreateOptions) (*v2.ProjectMember, error)
+	Update(ctx context.Context, projectMember *v2.ProjectMember, opts v1.UpdateOptions) (*v2.ProjectMember, error)
+	UpdateStatus(ctx context.Context, projectMember *v2.ProjectMember, opts v1.UpdateOptions) (*v2.ProjectMember, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v2.ProjectMember, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v2.ProjectMemberList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v2.ProjectMember, err error)
+	ProjectMemberExpansion
+}
+
+// projectMembers implements ProjectMemberInterface
+type projectMembers struct {
+	client rest.Interface
+	ns     string
+}
+
+// newProjectMembers returns a ProjectMembers
+func newProjectMembers(c *CagipV2Client, namespace string) *projectMembers {
+	return &projectMembers{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the projectMember, and returns the corresponding projectMember object, and an error if there is any.
+func (c *projectMembers) Get(ctx context.Context, name string, options v1.GetOptions) (result *v2.ProjectMember, err error) {
+	result = &v2.ProjectMember{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ProjectMembers that match those selectors.
+func (c *projectMembers) List(ctx context.Context, opts v1.ListOptions) (result *v2.ProjectMemberList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v2.ProjectMemberList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested projectMembers.
+func (c *projectMembers) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a projectMember and creates it.  Returns the server's representation of the projectMember, and an error, if there is any.
+func (c *projectMembers) Create(ctx context.Context, projectMember *v2.ProjectMember, opts v1.CreateOptions) (result *v2.ProjectMember, err error) {
+	result = &v2.ProjectMember{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(projectMember).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a projectMember and updates it. Returns the server's representation of the projectMember, and an error, if there is any.
+func (c *projectMembers) Update(ctx context.Context, projectMember *v2.ProjectMember, opts v1.UpdateOptions) (result *v2.ProjectMember, err error) {
+	result = &v2.ProjectMember{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		Name(projectMember.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(projectMember).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *projectMembers) UpdateStatus(ctx context.Context, projectMember *v2.ProjectMember, opts v1.UpdateOptions) (result *v2.ProjectMember, err error) {
+	result = &v2.ProjectMember{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		Name(projectMember.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(projectMember).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the projectMember and deletes it. Returns an error if one occurs.
+func (c *projectMembers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *projectMembers) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("projectmembers").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched projectMember.
+func (c *projectMembers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v2.ProjectMember, err error) {
+	result = &v2.ProjectMember{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("projectmembers").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -0,0 +1,73 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	"context"
+	time "time"
+
+	cagipv2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	versioned "github.com/ca-gip/kubi-members/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/ca-gip/kubi-members/pkg/generated/informers/externalversions/internalinterfaces"
+	v2 "github.com/ca-gip/kubi-members/pkg/generated/listers/cagip/v2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ClusterMemberInformer provides access to a shared informer and lister for
+// ClusterMembers.
+type ClusterMemberInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v2.ClusterMemberLister
+}
+
+type clusterMemberInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewClusterMemberInformer constructs a new informer for ClusterMember type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewClusterMemberInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredClusterMemberInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredClusterMemberInformer constructs a new informer for ClusterMember type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredClusterMemberInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CagipV2().ClusterMembers().List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CagipV2().ClusterMembers().Watch(context.TODO(), options)
+			},
+		},
+		&cagipv2.ClusterMember{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *clusterMemberInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredClusterMemberInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *clusterMemberInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&cagipv2.ClusterMember{}, f.defaultInformer)
+}
+
+func (f *clusterMemberInformer) Lister() v2.ClusterMemberLister {
+	return v2.NewClusterMemberLister(f.Informer().GetIndexer())
+}
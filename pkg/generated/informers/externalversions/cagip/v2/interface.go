@@ -0,0 +1,36 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	internalinterfaces "github.com/ca-gip/kubi-members/pkg/generated/informers/externalversions/internalinterfaces"
+)
+
+// Interface provides access to all the informers in this group version.
+type Interface interface {
+	// ClusterMembers returns a ClusterMemberInformer.
+	ClusterMembers() ClusterMemberInformer
+	// ProjectMembers returns a ProjectMemberInformer.
+	ProjectMembers() ProjectMemberInformer
+}
+
+type version struct {
+	factory          internalinterfaces.SharedInformerFactory
+	namespace        string
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// New returns a new Interface.
+func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakListOptions internalinterfaces.TweakListOptionsFunc) Interface {
+	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
+}
+
+// ClusterMembers returns a ClusterMemberInformer.
+func (v *version) ClusterMembers() ClusterMemberInformer {
+	return &clusterMemberInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// ProjectMembers returns a ProjectMemberInformer.
+func (v *version) ProjectMembers() ProjectMemberInformer {
+	return &projectMemberInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
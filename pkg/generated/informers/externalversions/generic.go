@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	v1 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v1"
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -42,6 +43,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	case v1.SchemeGroupVersion.WithResource("projectmembers"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Cagip().V1().ProjectMembers().Informer()}, nil
 
+		// Group=cagip.github.com, Version=v2
+	case v2.SchemeGroupVersion.WithResource("clustermembers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cagip().V2().ClusterMembers().Informer()}, nil
+	case v2.SchemeGroupVersion.WithResource("projectmembers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cagip().V2().ProjectMembers().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
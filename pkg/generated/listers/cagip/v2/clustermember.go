@@ -0,0 +1,52 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	v2 "github.com/ca-gip/kubi-members/pkg/apis/cagip/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ClusterMemberLister helps list ClusterMembers.
+// All objects returned here must be treated as read-only.
+type ClusterMemberLister interface {
+	// List lists all ClusterMembers in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v2.ClusterMember, err error)
+	// Get retrieves the ClusterMember from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v2.ClusterMember, error)
+	ClusterMemberListerExpansion
+}
+
+// clusterMemberLister implements the ClusterMemberLister interface.
+type clusterMemberLister struct {
+	indexer cache.Indexer
+}
+
+// NewClusterMemberLister returns a new ClusterMemberLister.
+func NewClusterMemberLister(indexer cache.Indexer) ClusterMemberLister {
+	return &clusterMemberLister{indexer: indexer}
+}
+
+// List lists all ClusterMembers in the indexer.
+func (s *clusterMemberLister) List(selector labels.Selector) (ret []*v2.ClusterMember, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v2.ClusterMember))
+	})
+	return ret, err
+}
+
+// Get retrieves the ClusterMember from the index for a given name.
+func (s *clusterMemberLister) Get(name string) (*v2.ClusterMember, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v2.Resource("clustermember"), name)
+	}
+	return obj.(*v2.ClusterMember), nil
+}
@@ -0,0 +1,15 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v2
+
+// ClusterMemberListerExpansion allows custom methods to be added to
+// ClusterMemberLister.
+type ClusterMemberListerExpansion interface{}
+
+// ProjectMemberListerExpansion allows custom methods to be added to
+// ProjectMemberLister.
+type ProjectMemberListerExpansion interface{}
+
+// ProjectMemberNamespaceListerExpansion allows custom methods to be added to
+// ProjectMemberNamespaceLister.
+type ProjectMemberNamespaceListerExpansion interface{}